@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/willjrcom/gfood-printer/internal/escpos"
+	"github.com/willjrcom/gfood-printer/internal/service/rabbitmq"
+)
+
+// Padrão do aviso sonoro
+const (
+	defaultAlertBeeps      = 3
+	defaultAlertDurationMs = 200
+	maxAlertDurationMs     = 450
+)
+
+// AlertConfig liga o aviso sonoro (buzzer ou luz) das impressoras de cozinha
+// nos jobs das exchanges configuradas. O comando vem do buzzer do perfil da
+// impressora que imprimiu; impressoras sem buzzer imprimem sem aviso.
+type AlertConfig struct {
+	Exchanges  []string                `json:"exchanges,omitempty"`   // padrão: print.order
+	Patterns   map[string]AlertPattern `json:"patterns,omitempty"`    // por estação ou impressora; "*" vale para as demais
+	QuietHours *QuietHours             `json:"quiet_hours,omitempty"` // horário sem aviso
+}
+
+// AlertPattern é o aviso de uma estação. Sem padrões configurados, todas as
+// estações usam o padrão (3 bipes de 200ms).
+type AlertPattern struct {
+	Beeps      int  `json:"beeps,omitempty"`       // 1 a 9 (padrão 3)
+	DurationMs int  `json:"duration_ms,omitempty"` // duração de cada bipe, até 450 (padrão 200)
+	Silent     bool `json:"silent,omitempty"`      // estação sem aviso
+}
+
+// QuietHours é o intervalo do dia (HH:MM) em que o aviso não toca; pode
+// passar da meia-noite (22:00 a 06:00)
+type QuietHours struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// Validate verifica os padrões e o horário de silêncio
+func (ac *AlertConfig) Validate() error {
+	for name, p := range ac.Patterns {
+		if p.Beeps < 0 || p.Beeps > 9 {
+			return fmt.Errorf("padrão %s: beeps deve estar entre 1 e 9", name)
+		}
+		if p.DurationMs < 0 || p.DurationMs > maxAlertDurationMs {
+			return fmt.Errorf("padrão %s: duration_ms deve ser no máximo %d", name, maxAlertDurationMs)
+		}
+	}
+	if q := ac.QuietHours; q != nil {
+		if _, err := parseClock(q.Start); err != nil {
+			return fmt.Errorf("quiet_hours.start: %v", err)
+		}
+		if _, err := parseClock(q.End); err != nil {
+			return fmt.Errorf("quiet_hours.end: %v", err)
+		}
+	}
+	return nil
+}
+
+// appliesTo indica se os jobs da exchange recebem o aviso
+func (ac *AlertConfig) appliesTo(exchange string) bool {
+	if ac == nil {
+		return false
+	}
+	exchanges := ac.Exchanges
+	if len(exchanges) == 0 {
+		exchanges = []string{rabbitmq.ORDER_EX}
+	}
+	for _, ex := range exchanges {
+		if ex == exchange {
+			return true
+		}
+	}
+	return false
+}
+
+// pattern retorna o aviso do destino pedido (estação ou grupo) ou, sem
+// padrão para ele, da impressora que imprimiu
+func (ac *AlertConfig) pattern(requested, printerName string) (AlertPattern, bool) {
+	if len(ac.Patterns) == 0 {
+		return AlertPattern{Beeps: defaultAlertBeeps, DurationMs: defaultAlertDurationMs}, true
+	}
+	p, ok := ac.Patterns[targetLabel(requested)]
+	if !ok {
+		p, ok = ac.Patterns[printerName]
+	}
+	if !ok {
+		p, ok = ac.Patterns["*"]
+	}
+	if !ok || p.Silent {
+		return AlertPattern{}, false
+	}
+	if p.Beeps == 0 {
+		p.Beeps = defaultAlertBeeps
+	}
+	if p.DurationMs == 0 {
+		p.DurationMs = defaultAlertDurationMs
+	}
+	return p, true
+}
+
+// quiet indica se o horário está dentro do intervalo de silêncio
+func (ac *AlertConfig) quiet(now time.Time) bool {
+	if ac.QuietHours == nil {
+		return false
+	}
+	start, _ := parseClock(ac.QuietHours.Start)
+	end, _ := parseClock(ac.QuietHours.End)
+	minute := now.Hour()*60 + now.Minute()
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// parseClock converte HH:MM em minutos do dia
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("horário inválido: %q (use HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// alertBytes monta o aviso do destino na impressora, vazio quando não há
+// aviso para a estação, a impressora não tem buzzer ou é horário de silêncio
+func alertBytes(requested, printerName string) []byte {
+	if GlobalConfig == nil || GlobalConfig.Alerts == nil {
+		return nil
+	}
+	ac := GlobalConfig.Alerts
+	pattern, ok := ac.pattern(requested, printerName)
+	if !ok || ac.quiet(time.Now()) {
+		return nil
+	}
+	settings := printerSettings(printerName)
+	mode := settings.profile().Buzzer
+	if mode == "" || mode == escpos.BuzzerNone {
+		return nil
+	}
+	var buf escpos.Buffer
+	buf.Beep(mode, pattern.Beeps, pattern.DurationMs, settings.buzzerPin())
+	log.Printf("Aviso: %d bipe(s) em [%s] (%s)", pattern.Beeps, printerName, mode)
+	return buf.Bytes()
+}
+
+// validateDrawerBuzzer recusa o aviso pela porta da gaveta na impressora do
+// drawer_printer quando ele pulsaria o pino da gaveta: cada pedido abriria a
+// gaveta do caixa
+func validateDrawerBuzzer(c *Config) error {
+	if c.DrawerPrinter == "" {
+		return nil
+	}
+	name := c.DrawerPrinter
+	if printer, ok := c.Stations[name]; ok {
+		name = printer
+	}
+	if g, ok := c.Groups[name]; ok && len(g.Printers) > 0 {
+		name = g.Printers[0]
+	}
+	p, ok := c.Printers[name]
+	if !ok {
+		p = c.Printers["*"]
+	}
+
+	mode := p.Buzzer
+	if mode == "" {
+		if profile, ok := c.Profiles[p.Profile]; ok {
+			mode = profile.Buzzer
+		} else if profile, ok := escpos.LookupProfile(p.Profile); ok {
+			mode = profile.Buzzer
+		}
+	}
+	if mode != escpos.BuzzerDrawer {
+		return nil
+	}
+	if pin, _, _ := p.drawerPulse(); p.buzzerPin() == pin {
+		return fmt.Errorf("Impressora %s: buzzer drawer no pino da gaveta (%d) abriria a gaveta a cada pedido; use outro buzzer_pin", name, pin)
+	}
+	return nil
+}
+
+// withAlert acrescenta o aviso ao fim do job, depois de todas as cópias
+func withAlert(render renderFunc, requested string) renderFunc {
+	return func(printerName string) ([]byte, error) {
+		data, err := render(printerName)
+		if err != nil {
+			return nil, err
+		}
+		return append(data, alertBytes(requested, printerName)...), nil
+	}
+}
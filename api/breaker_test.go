@@ -0,0 +1,64 @@
+package api
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	const cooldown = 20 * time.Millisecond
+	b := NewCircuitBreaker(2, cooldown)
+
+	// closed → open depois de Threshold falhas seguidas
+	if err := b.Allow(); err != nil {
+		t.Fatalf("closed: %v", err)
+	}
+	b.Failure()
+	if b.State() != CircuitClosed || b.Allow() != nil {
+		t.Fatalf("opened after 1 failure: %s", b.State())
+	}
+	b.Failure()
+	if b.State() != CircuitOpen {
+		t.Fatalf("state %s after 2 failures, want open", b.State())
+	}
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("open: Allow = %v", err)
+	}
+
+	// open → half_open depois do cooldown, com uma única requisição de teste
+	time.Sleep(cooldown)
+	if b.State() != CircuitHalfOpen {
+		t.Fatalf("state %s after cooldown, want half_open", b.State())
+	}
+	if err := b.Allow(); err != nil {
+		t.Fatalf("half_open trial: %v", err)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second request during the trial: Allow = %v", err)
+	}
+
+	// Sucesso no teste fecha o circuito e zera as falhas
+	b.Success()
+	if b.State() != CircuitClosed {
+		t.Fatalf("state %s after trial success, want closed", b.State())
+	}
+	b.Failure()
+	if b.State() != CircuitClosed {
+		t.Fatal("failures were not reset on success")
+	}
+
+	// Falha no teste reabre o circuito na hora, com novo cooldown
+	b.Failure()
+	time.Sleep(cooldown)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("half_open trial: %v", err)
+	}
+	b.Failure()
+	if b.State() != CircuitOpen {
+		t.Fatalf("state %s after trial failure, want open", b.State())
+	}
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("reopened: Allow = %v", err)
+	}
+}
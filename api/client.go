@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+)
+
+type Config interface {
+	GetAccessToken() string
+	GetSchemaName() string
+	GetBackendURL() string
+}
+
+// sharedTransport é reaproveitado por todos os clientes para manter as conexões abertas
+var sharedTransport = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	DialContext: (&net.Dialer{
+		Timeout:   5 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	MaxIdleConns:          20,
+	MaxIdleConnsPerHost:   10,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   5 * time.Second,
+	ResponseHeaderTimeout: 10 * time.Second,
+}
+
+// Client chama o backend com retry, backoff exponencial e circuit breaker
+type Client struct {
+	config     Config
+	httpClient *http.Client
+	breaker    *CircuitBreaker
+
+	MaxAttempts int           // tentativas por chamada
+	BaseBackoff time.Duration // espera antes da segunda tentativa, dobrando a cada falha
+	MaxBackoff  time.Duration
+}
+
+func NewClient(config Config) *Client {
+	return &Client{
+		config:      config,
+		httpClient:  &http.Client{Transport: sharedTransport, Timeout: 10 * time.Second},
+		breaker:     NewCircuitBreaker(5, 30*time.Second),
+		MaxAttempts: 3,
+		BaseBackoff: 500 * time.Millisecond,
+		MaxBackoff:  5 * time.Second,
+	}
+}
+
+// Breaker expõe o circuit breaker para pausar o consumo enquanto o backend está fora
+func (c *Client) Breaker() *CircuitBreaker {
+	return c.breaker
+}
+
+// response guarda o corpo e os cabeçalhos de uma resposta 200
+type response struct {
+	body   []byte
+	header http.Header
+}
+
+// get faz um GET autenticado em backend_url + path, retentando falhas transitórias
+func (c *Client) get(ctx context.Context, path string) (*response, error) {
+	var lastErr error
+	for attempt := 0; attempt < c.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			if err := sleepContext(ctx, c.backoff(attempt)); err != nil {
+				return nil, transportError(err)
+			}
+		}
+
+		if err := c.breaker.Allow(); err != nil {
+			return nil, err
+		}
+
+		resp, err := c.doGet(ctx, path)
+		if err == nil {
+			c.breaker.Success()
+			return resp, nil
+		}
+
+		lastErr = err
+		if !IsRetryable(err) {
+			// Erros permanentes (4xx) e token recusado não indicam backend fora do ar
+			c.breaker.Success()
+			return nil, err
+		}
+		c.breaker.Failure()
+	}
+	return nil, lastErr
+}
+
+func (c *Client) doGet(ctx context.Context, path string) (*response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.config.GetBackendURL()+path, nil)
+	if err != nil {
+		return nil, &Error{Err: err}
+	}
+	req.Header.Set("access-token", c.config.GetAccessToken())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, transportError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, statusError(resp.StatusCode, string(body))
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, transportError(err)
+	}
+	return &response{body: content, header: resp.Header}, nil
+}
+
+// backoff calcula a espera exponencial com jitter para a tentativa informada
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.BaseBackoff << (attempt - 1)
+	if d > c.MaxBackoff || d <= 0 {
+		d = c.MaxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+)
+
+// ErrCircuitOpen é retornado enquanto o circuito está aberto (backend fora do ar)
+var ErrCircuitOpen = errors.New("backend indisponível: circuito aberto")
+
+// Error descreve uma falha ao chamar o backend, indicando se vale tentar de novo
+type Error struct {
+	StatusCode int    // 0 quando a falha aconteceu antes de receber resposta
+	Body       string // corpo da resposta de erro, quando houver
+	Retryable  bool
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("erro no backend (Status %d): %s", e.StatusCode, e.Body)
+	}
+	return fmt.Sprintf("erro ao buscar dados no backend: %v", e.Err)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// IsRetryable indica se o erro é transitório (timeout, rede, 5xx, 429, circuito aberto)
+func IsRetryable(err error) bool {
+	if errors.Is(err, ErrCircuitOpen) {
+		return true
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable
+	}
+	return false
+}
+
+// IsAuthError indica que o backend recusou o access-token (401/403); a
+// requisição deve ser repetida depois que o token for renovado
+func IsAuthError(err error) bool {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden
+	}
+	return false
+}
+
+// IsForbidden indica 403: o token é válido, mas não tem permissão para o recurso
+func IsForbidden(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden
+}
+
+// statusError classifica uma resposta de erro do backend
+func statusError(statusCode int, body string) *Error {
+	return &Error{
+		StatusCode: statusCode,
+		Body:       body,
+		Retryable:  statusCode >= 500 || statusCode == http.StatusTooManyRequests || statusCode == http.StatusRequestTimeout,
+	}
+}
+
+// transportError classifica falhas de rede
+func transportError(err error) *Error {
+	return &Error{Err: err, Retryable: retryableTransport(err)}
+}
+
+// retryableTransport indica falhas transitórias: timeout, conexão recusada,
+// resetada ou que não completou. URL inválida, esquema não suportado e
+// certificado recusado (x509/TLS) são permanentes e falham na hora.
+// Cancelamento do contexto não é retentado.
+func retryableTransport(err error) bool {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return false
+	case errors.Is(err, context.DeadlineExceeded):
+		return true
+	case isCertificateError(err):
+		return false
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET):
+		return true
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		// Conexão fechada pelo servidor no meio da resposta
+		return true
+	}
+
+	// Todo erro do http.Client é um *url.Error, que sempre implementa
+	// net.Error: classifica pelo erro de dentro
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return opErr.Op == "dial" || opErr.Op == "read" || opErr.Op == "write"
+	}
+	return false
+}
+
+func isCertificateError(err error) bool {
+	var verifyErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	return errors.As(err, &verifyErr) || errors.As(err, &authorityErr) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &invalidErr)
+}
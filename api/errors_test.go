@@ -0,0 +1,107 @@
+package api
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		status    int
+		retryable bool
+	}{
+		{http.StatusBadRequest, false},
+		{http.StatusUnauthorized, false},
+		{http.StatusForbidden, false},
+		{http.StatusNotFound, false},
+		{http.StatusUnprocessableEntity, false},
+		{http.StatusRequestTimeout, true},
+		{http.StatusTooManyRequests, true},
+		{http.StatusInternalServerError, true},
+		{http.StatusBadGateway, true},
+		{http.StatusServiceUnavailable, true},
+	}
+	for _, tt := range tests {
+		err := statusError(tt.status, "corpo")
+		if err.Retryable != tt.retryable || IsRetryable(err) != tt.retryable {
+			t.Errorf("%d: retryable %v, want %v", tt.status, err.Retryable, tt.retryable)
+		}
+	}
+	if !IsAuthError(statusError(401, "")) || !IsAuthError(statusError(403, "")) || IsAuthError(statusError(404, "")) {
+		t.Error("IsAuthError misclassified 401/403/404")
+	}
+	if !IsRetryable(fmt.Errorf("pedido: %w", ErrCircuitOpen)) {
+		t.Error("open circuit must be retryable")
+	}
+}
+
+// requestError faz uma requisição de verdade e retorna o erro do http.Client
+func requestError(t *testing.T, client *http.Client, ctx context.Context, rawURL string) error {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatalf("%s: request succeeded", rawURL)
+	}
+	return err
+}
+
+func TestRetryableTransport(t *testing.T) {
+	// Porta sem ninguém escutando: conexão recusada
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedAddr := ln.Addr().String()
+	ln.Close()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	ctx := context.Background()
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"connection refused", requestError(t, http.DefaultClient, ctx, "http://"+closedAddr), true},
+		{"client timeout", requestError(t, &http.Client{Timeout: 20 * time.Millisecond}, ctx, slow.URL), true},
+		{"context canceled", requestError(t, http.DefaultClient, canceled, slow.URL), false},
+		{"unsupported scheme", requestError(t, http.DefaultClient, ctx, "ftp://127.0.0.1/pedido"), false},
+		{"unknown authority", &url.Error{Op: "Get", URL: "https://api", Err: x509.UnknownAuthorityError{}}, false},
+		{"hostname mismatch", &url.Error{Op: "Get", URL: "https://api", Err: x509.HostnameError{Host: "api"}}, false},
+		{"connection reset", &url.Error{Op: "Get", URL: "http://api", Err: &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}}, true},
+		{"deadline exceeded", &url.Error{Op: "Get", URL: "http://api", Err: context.DeadlineExceeded}, true},
+		{"unexpected EOF", &url.Error{Op: "Get", URL: "http://api", Err: io.ErrUnexpectedEOF}, true},
+		{"dns lookup", &url.Error{Op: "Get", URL: "http://api", Err: &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "api"}}}, true},
+		{"other", errors.New("falha"), false},
+	}
+	for _, tt := range tests {
+		if got := retryableTransport(tt.err); got != tt.retryable {
+			t.Errorf("%s (%v): retryable %v, want %v", tt.name, tt.err, got, tt.retryable)
+		}
+		if got := IsRetryable(transportError(tt.err)); got != tt.retryable {
+			t.Errorf("%s: IsRetryable %v, want %v", tt.name, got, tt.retryable)
+		}
+	}
+}
@@ -1,44 +1,48 @@
 package api
 
 import (
+	"context"
+	"encoding/base64"
 	"fmt"
-	"io"
-	"net/http"
-	"time"
+	"mime"
+	"strings"
 )
 
-type Config interface {
-	GetAccessToken() string
-	GetSchemaName() string
-	GetBackendURL() string
+// PrintContent é o conteúdo de impressão retornado pelo backend
+type PrintContent struct {
+	Data        []byte
+	ContentType string // media type sem parâmetros (ex.: text/plain, application/vnd.escpos)
+	Charset     string // parâmetro charset do Content-Type, quando informado
 }
 
-func FetchPrintContent(config Config, path string) (string, error) {
-	url := config.GetBackendURL() + path
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return "", fmt.Errorf("erro ao criar requisição: %v", err)
-	}
-
-	req.Header.Set("access-token", config.GetAccessToken())
-
-	resp, err := client.Do(req)
+// FetchPrintContent busca o conteúdo de impressão em backend_url + path,
+// decodificando o corpo conforme o Content-Transfer-Encoding
+func (c *Client) FetchPrintContent(ctx context.Context, path string) (*PrintContent, error) {
+	resp, err := c.get(ctx, path)
 	if err != nil {
-		return "", fmt.Errorf("erro ao buscar dados no backend: %v", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("erro no backend (Status %d): %s", resp.StatusCode, string(body))
+	content := &PrintContent{Data: resp.body}
+	if ct := resp.header.Get("Content-Type"); ct != "" {
+		mediaType, params, err := mime.ParseMediaType(ct)
+		if err == nil {
+			content.ContentType = mediaType
+			content.Charset = params["charset"]
+		}
 	}
 
-	content, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("erro ao ler corpo da resposta: %v", err)
+	switch strings.ToLower(strings.TrimSpace(resp.header.Get("Content-Transfer-Encoding"))) {
+	case "", "binary", "8bit", "7bit", "identity":
+	case "base64":
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(resp.body)))
+		if err != nil {
+			return nil, &Error{Err: fmt.Errorf("conteúdo base64 inválido: %v", err)}
+		}
+		content.Data = decoded
+	default:
+		return nil, &Error{Err: fmt.Errorf("Content-Transfer-Encoding não suportado: %s", resp.header.Get("Content-Transfer-Encoding"))}
 	}
 
-	return string(content), nil
+	return content, nil
 }
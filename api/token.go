@@ -0,0 +1,55 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token,omitempty"`
+	SchemaName   string `json:"schema_name"`
+}
+
+type refreshResponse struct {
+	AccessToken string `json:"access_token"`
+}
+
+// RefreshAccessToken pede um novo token em backend_url + path, enviando o token
+// atual e o refresh token (quando houver)
+func (c *Client) RefreshAccessToken(ctx context.Context, path, refreshToken string) (string, error) {
+	body, err := json.Marshal(refreshRequest{RefreshToken: refreshToken, SchemaName: c.config.GetSchemaName()})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.config.GetBackendURL()+path, bytes.NewReader(body))
+	if err != nil {
+		return "", &Error{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("access-token", c.config.GetAccessToken())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", transportError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", statusError(resp.StatusCode, string(respBody))
+	}
+
+	var result refreshResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("resposta inválida ao renovar token: %v", err)
+	}
+	if result.AccessToken == "" {
+		return "", fmt.Errorf("resposta sem access_token ao renovar token")
+	}
+	return result.AccessToken, nil
+}
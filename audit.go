@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const auditFileName = "audit.log"
+
+var auditMu sync.Mutex
+
+// AuditEntry registra uma ação sensível (ex.: abertura da gaveta) com quem a
+// disparou. As entradas ficam em audit.log ao lado do executável, uma por linha.
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action"`
+	User      string    `json:"user,omitempty"`         // chamador autenticado (nome do token no HTTP)
+	Claimed   string    `json:"claimed_user,omitempty"` // usuário informado no pedido, sem verificação
+	Source    string    `json:"source"`                 // websocket ou http
+	Remote    string    `json:"remote,omitempty"`       // endereço de quem pediu
+	Printer   string    `json:"printer,omitempty"`
+	Requested string    `json:"requested,omitempty"`
+	JobID     string    `json:"job_id,omitempty"`
+	Status    string    `json:"status"` // ok ou error
+	Error     string    `json:"error,omitempty"`
+}
+
+func auditPath() string {
+	return filepath.Join(appDir(), auditFileName)
+}
+
+// recordAudit acrescenta a entrada ao audit.log e ao log do agente
+func recordAudit(entry AuditEntry) {
+	entry.Time = time.Now()
+	log.Printf("Auditoria: %s por %q/%q (%s %s) em [%s]: %s %s", entry.Action, entry.User, entry.Claimed, entry.Source, entry.Remote, entry.Printer, entry.Status, entry.Error)
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	f, err := os.OpenFile(auditPath(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Printf("Auditoria: Erro ao gravar %s: %v", auditFileName, err)
+		return
+	}
+	defer f.Close()
+	f.Write(append(line, '\n'))
+}
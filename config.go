@@ -0,0 +1,396 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/willjrcom/gfood-printer/internal/escpos"
+	"github.com/willjrcom/gfood-printer/internal/service/rabbitmq"
+)
+
+const configFileName = "config.json"
+
+// defaultListen aceita só conexões da própria máquina
+const defaultListen = "127.0.0.1:8089"
+
+type Config struct {
+	AccessToken   string         `json:"access_token"`
+	SchemaName    string         `json:"schema_name"`
+	BackendURL    string         `json:"backend_url"`
+	RabbitMQURL   string         `json:"rabbitmq_url"`
+	AgentID       string         `json:"agent_id,omitempty"`
+	Station       string         `json:"station,omitempty"` // estação padrão do agente nas inscrições topic
+	Subscriptions []Subscription `json:"subscriptions,omitempty"`
+
+	MaxInlineBytes int `json:"max_inline_bytes,omitempty"` // limite do conteúdo inline nas mensagens
+
+	// Configurações locais por impressora ("*" vale para as não listadas)
+	Printers map[string]PrinterConfig `json:"printers,omitempty"`
+
+	// Estações lógicas (cozinha, bar, caixa) e a impressora local de cada uma
+	Stations map[string]string `json:"stations,omitempty"`
+
+	// Grupos de impressoras com política strict, failover ou broadcast
+	Groups map[string]PrinterGroup `json:"groups,omitempty"`
+
+	// Divisão dos pedidos entre as estações pelos itens
+	Routing *RoutingConfig `json:"routing,omitempty"`
+
+	// Aviso sonoro (buzzer ou luz) nos pedidos das exchanges configuradas
+	Alerts *AlertConfig `json:"alerts,omitempty"`
+
+	// Impressora ou estação ligada à gaveta, usada no open_drawer sem printer
+	DrawerPrinter string `json:"drawer_printer,omitempty"`
+
+	// Tokens aceitos no POST /drawer/open além do access_token: nome do
+	// chamador (registrado na auditoria) → token
+	DrawerTokens map[string]string `json:"drawer_tokens,omitempty"`
+
+	// Endereço do servidor WebSocket/HTTP (padrão 127.0.0.1:8089, só a
+	// própria máquina); use :8089 para aceitar conexões da rede
+	Listen string `json:"listen,omitempty"`
+
+	// Impressoras virtuais: gravam os jobs em uma pasta (testes e arquivo)
+	VirtualPrinters map[string]VirtualPrinter `json:"virtual_printers,omitempty"`
+
+	// Perfis de impressora definidos pelo usuário, além do catálogo embutido
+	Profiles map[string]escpos.Profile `json:"profiles,omitempty"`
+
+	// Renovação do access_token: POST em backend_url + token_refresh_path
+	TokenRefreshPath string `json:"token_refresh_path,omitempty"`
+	RefreshToken     string `json:"refresh_token,omitempty"`
+
+	tokenMu sync.RWMutex
+}
+
+// Subscription liga o agente a uma exchange do RabbitMQ
+type Subscription struct {
+	Exchange   string `json:"exchange"`
+	Type       string `json:"type,omitempty"`        // direct (padrão) ou topic
+	RoutingKey string `json:"routing_key,omitempty"` // direct: schema_name; topic: <schema>.<estação>.#
+	Station    string `json:"station,omitempty"`     // topic: estação atendida por este agente
+	Queue      string `json:"queue,omitempty"`       // topic: padrão <exchange>_<schema>_<agent_id>_queue
+	Printer    string `json:"printer,omitempty"`     // usada quando a mensagem não informa printer_name
+	Template   string `json:"template,omitempty"`    // template local aplicado aos dados das mensagens
+}
+
+// Binding converte a inscrição para a declaração de fila do RabbitMQ
+func (s Subscription) Binding() rabbitmq.Binding {
+	return rabbitmq.Binding{
+		Exchange:     s.Exchange,
+		ExchangeType: s.Type,
+		Queue:        s.Queue,
+		RoutingKeys:  []string{s.RoutingKey},
+	}
+}
+
+func (c *Config) GetAccessToken() string {
+	c.tokenMu.RLock()
+	defer c.tokenMu.RUnlock()
+	return c.AccessToken
+}
+
+// SetAccessToken troca o token usado nas chamadas ao backend
+func (c *Config) SetAccessToken(token string) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.AccessToken = token
+}
+
+func (c *Config) GetSchemaName() string { return c.SchemaName }
+func (c *Config) GetBackendURL() string { return c.BackendURL }
+
+// liveConfig lê sempre o GlobalConfig atual. As alterações feitas pelo
+// WebSocket trocam a configuração inteira, então o cliente do backend não
+// pode guardar o ponteiro da configuração em que foi criado.
+type liveConfig struct{}
+
+func (liveConfig) GetAccessToken() string { return GlobalConfig.GetAccessToken() }
+func (liveConfig) GetSchemaName() string  { return GlobalConfig.GetSchemaName() }
+func (liveConfig) GetBackendURL() string  { return GlobalConfig.GetBackendURL() }
+
+// GetAgentID retorna o identificador do agente, usando o hostname quando não configurado
+func (c *Config) GetAgentID() string {
+	if c.AgentID != "" {
+		return c.AgentID
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "gfood-printer"
+	}
+	return hostname
+}
+
+// GetMaxInlineBytes retorna o limite do conteúdo inline das mensagens
+func (c *Config) GetMaxInlineBytes() int {
+	if c.MaxInlineBytes > 0 {
+		return c.MaxInlineBytes
+	}
+	return defaultMaxInlineBytes
+}
+
+// GetSubscriptions retorna as inscrições configuradas já com os valores padrão
+// aplicados. Sem configuração, consome as três exchanges de impressão.
+func (c *Config) GetSubscriptions() []Subscription {
+	subs := c.Subscriptions
+	if len(subs) == 0 {
+		subs = []Subscription{
+			{Exchange: rabbitmq.SHIFT_EX},
+			{Exchange: rabbitmq.GROUP_ITEM_EX},
+			{Exchange: rabbitmq.ORDER_EX},
+		}
+	}
+
+	result := make([]Subscription, 0, len(subs))
+	for _, s := range subs {
+		if s.Type == "topic" {
+			// Cada agente tem a própria fila, assim dois agentes do mesmo
+			// schema não disputam as mensagens um do outro
+			if s.Station == "" {
+				s.Station = c.Station
+			}
+			if s.RoutingKey == "" {
+				if s.Station != "" {
+					s.RoutingKey = fmt.Sprintf("%s.%s.#", c.SchemaName, s.Station)
+				} else {
+					s.RoutingKey = fmt.Sprintf("%s.#", c.SchemaName)
+				}
+			}
+			if s.Queue == "" {
+				s.Queue = fmt.Sprintf("%s_%s_%s_queue", s.Exchange, c.SchemaName, c.GetAgentID())
+			}
+		} else if s.RoutingKey == "" {
+			s.RoutingKey = c.SchemaName
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+// complete indica se os campos obrigatórios estão preenchidos
+func (c *Config) complete() bool {
+	return c.AccessToken != "" && c.SchemaName != "" && c.BackendURL != "" && c.RabbitMQURL != ""
+}
+
+// Validate verifica se a configuração tem os campos obrigatórios
+func (c *Config) Validate() error {
+	if !c.complete() {
+		return fmt.Errorf("Configuração incompleta. access_token, schema_name, backend_url e rabbitmq_url são obrigatórios.")
+	}
+
+	if c.MaxInlineBytes < 0 {
+		return fmt.Errorf("max_inline_bytes não pode ser negativo")
+	}
+
+	for name, v := range c.VirtualPrinters {
+		if !virtualNameRe.MatchString(name) || name == "default" {
+			return fmt.Errorf("Nome de impressora virtual inválido: %q", name)
+		}
+		if err := v.Validate(); err != nil {
+			return fmt.Errorf("Impressora virtual %s: %v", name, err)
+		}
+	}
+
+	for name, printer := range c.Stations {
+		if err := validateStation(c, name, printer); err != nil {
+			return err
+		}
+	}
+
+	for name, g := range c.Groups {
+		if err := validateGroup(c, name, g); err != nil {
+			return err
+		}
+	}
+
+	if c.Routing != nil {
+		if err := c.Routing.Validate(); err != nil {
+			return fmt.Errorf("Roteamento: %v", err)
+		}
+		if len(c.Routing.Rules) > 0 && !c.Routing.hasTemplate(c.GetSubscriptions()) {
+			return fmt.Errorf("Roteamento: nenhuma inscrição das exchanges roteadas tem template")
+		}
+	}
+
+	if c.Alerts != nil {
+		if err := c.Alerts.Validate(); err != nil {
+			return fmt.Errorf("Avisos: %v", err)
+		}
+	}
+
+	for name, p := range c.Profiles {
+		if err := validateProfile(name, p); err != nil {
+			return fmt.Errorf("Perfil %s: %v", name, err)
+		}
+	}
+
+	for name, p := range c.Printers {
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("Impressora %s: %v", name, err)
+		}
+		if name == "*" && p.Address != "" {
+			return fmt.Errorf("Impressora *: address só vale para impressoras nomeadas")
+		}
+		if _, ok := c.VirtualPrinters[name]; ok && p.Address != "" {
+			return fmt.Errorf("Impressora %s: address não vale para impressoras virtuais", name)
+		}
+		if p.Profile != "" && !c.hasProfile(p.Profile) {
+			return fmt.Errorf("Impressora %s: perfil desconhecido: %s", name, p.Profile)
+		}
+	}
+	if err := validateDrawerBuzzer(c); err != nil {
+		return err
+	}
+
+	for i, s := range c.Subscriptions {
+		if strings.TrimSpace(s.Exchange) == "" {
+			return fmt.Errorf("Inscrição %d sem exchange", i)
+		}
+		if s.Type != "" && s.Type != "direct" && s.Type != "topic" {
+			return fmt.Errorf("Inscrição %s com tipo inválido: %s (use direct ou topic)", s.Exchange, s.Type)
+		}
+	}
+
+	// Compara com os valores padrão aplicados: uma inscrição com a routing
+	// key vazia e outra com a key padrão escrita são a mesma
+	seen := map[string]bool{}
+	for _, s := range c.GetSubscriptions() {
+		key := s.Exchange + "|" + s.Type + "|" + s.RoutingKey + "|" + s.Station + "|" + s.Queue
+		if seen[key] {
+			return fmt.Errorf("Inscrição duplicada: %s (%s)", s.Exchange, s.RoutingKey)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// resetFields limpa os mapas e objetos presentes em fields antes da
+// decodificação, que senão mesclaria as entradas recebidas com as atuais
+func (c *Config) resetFields(fields map[string]interface{}) {
+	for name := range fields {
+		switch name {
+		case "printers":
+			c.Printers = nil
+		case "stations":
+			c.Stations = nil
+		case "groups":
+			c.Groups = nil
+		case "routing":
+			c.Routing = nil
+		case "alerts":
+			c.Alerts = nil
+		case "drawer_tokens":
+			c.DrawerTokens = nil
+		case "virtual_printers":
+			c.VirtualPrinters = nil
+		case "profiles":
+			c.Profiles = nil
+		}
+	}
+}
+
+// Clone retorna uma cópia independente da configuração
+func (c *Config) Clone() *Config {
+	c.tokenMu.RLock()
+	data, err := json.Marshal(c)
+	c.tokenMu.RUnlock()
+
+	clone := &Config{}
+	if err == nil {
+		json.Unmarshal(data, clone)
+	}
+	return clone
+}
+
+// appDir retorna a pasta do executável. Durante o `go run` o binário fica em
+// uma pasta temporária, então usa a pasta atual.
+func appDir() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return "."
+	}
+	dir := filepath.Dir(exe)
+	if strings.HasPrefix(dir, os.TempDir()) {
+		if wd, err := os.Getwd(); err == nil {
+			return wd
+		}
+	}
+	return dir
+}
+
+func configPath() string {
+	return filepath.Join(appDir(), configFileName)
+}
+
+// loadConfig lê o config.json ao lado do executável
+func loadConfig() (*Config, error) {
+	data, err := os.ReadFile(configPath())
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar %s: %v", configFileName, err)
+	}
+	return &config, nil
+}
+
+// saveConfig grava a configuração no config.json ao lado do executável
+func saveConfig(config *Config) error {
+	config.tokenMu.RLock()
+	data, err := json.MarshalIndent(config, "", "  ")
+	config.tokenMu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	tmp := configPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("erro ao gravar %s: %v", configFileName, err)
+	}
+	return os.Rename(tmp, configPath())
+}
+
+// configMu serializa as alterações da configuração: a cópia, a gravação e a
+// troca do GlobalConfig acontecem sob o mesmo lock
+var configMu sync.Mutex
+
+// updateConfig aplica a alteração em uma cópia da configuração atual, valida
+// a cópia inteira, grava o config.json e só então troca o GlobalConfig. Quem
+// já leu o ponteiro antigo continua com uma configuração consistente, e o
+// arquivo gravado sempre é aceito na próxima inicialização.
+func updateConfig(change func(config *Config) error) error {
+	configMu.Lock()
+	defer configMu.Unlock()
+	if GlobalConfig == nil {
+		return fmt.Errorf("Agente ainda não configurado")
+	}
+
+	config := GlobalConfig.Clone()
+	if err := change(config); err != nil {
+		return err
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	if err := saveConfig(config); err != nil {
+		return err
+	}
+	GlobalConfig = config
+	return nil
+}
+
+// printerFromRoutingKey extrai a impressora de routing keys no formato
+// <schema>.<estação>.<impressora>
+func printerFromRoutingKey(routingKey string) string {
+	parts := strings.SplitN(routingKey, ".", 3)
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[2]
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/willjrcom/gfood-printer/internal/escpos"
+)
+
+// Acompanhamento dos jobs enviados pelo lp: o CUPS aceita o job mesmo com a
+// fila parada, então o resultado real vem das consultas ao lpstat.
+const (
+	cupsPollInterval   = 3 * time.Second
+	cupsTrackTimeout   = time.Hour        // desiste de acompanhar jobs presos há mais tempo
+	cupsEnableInterval = 30 * time.Second // intervalo mínimo entre dois cupsenable da mesma fila
+	cupsCommandTimeout = 10 * time.Second
+)
+
+// Situação do job na fila do CUPS (system_state)
+const (
+	CUPSPending    = "pending"
+	CUPSProcessing = "processing"
+	CUPSHeld       = "held"
+	CUPSStopped    = "stopped" // fila parada (impressora desabilitada)
+	CUPSCompleted  = "completed"
+	CUPSCanceled   = "canceled"
+	CUPSAborted    = "aborted"
+)
+
+// StateStopped é o status de uma impressora do CUPS com a fila parada
+const StateStopped = "stopped"
+
+// EventJobUpdated avisa os navegadores conectados quando a situação de um job muda
+const EventJobUpdated = "job_updated"
+
+// lp responde "request id is EPSON-12 (1 file(s))" (ou a tradução em português)
+var lpRequestIDRe = regexp.MustCompile(`(?:request id is|id de requisição é)\s+(\S+)`)
+
+// parseLPRequestID extrai o id do job da saída do lp
+func parseLPRequestID(output string) string {
+	if m := lpRequestIDRe.FindStringSubmatch(output); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// cupsQueue retorna a fila de um id de job do CUPS (<fila>-<número>)
+func cupsQueue(jobID string) string {
+	if i := strings.LastIndex(jobID, "-"); i > 0 {
+		return jobID[:i]
+	}
+	return ""
+}
+
+// cupsJob é um job listado pelo lpstat -l -o
+type cupsJob struct {
+	ID      string
+	Reasons []string // job-state-reasons (linha Alerts)
+	Message string   // linha Status
+}
+
+func (j cupsJob) hasReason(substr string) bool {
+	for _, r := range j.Reasons {
+		if strings.Contains(r, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// finalState classifica um job que saiu da fila pela linha Alerts. Sem
+// histórico de jobs no CUPS, o job que saiu da fila é dado como impresso.
+func (j cupsJob) finalState() (state, reason string) {
+	switch {
+	case j.hasReason("canceled"):
+		return CUPSCanceled, j.Message
+	case j.hasReason("aborted"):
+		return CUPSAborted, j.Message
+	default:
+		return CUPSCompleted, ""
+	}
+}
+
+// cupsPrinterState é a situação de uma fila pelo lpstat -p
+type cupsPrinterState struct {
+	Enabled bool
+	Reason  string
+}
+
+// lpstat executa o lpstat em inglês, para a saída ter um formato só
+func lpstat(args ...string) (string, error) {
+	cmd := exec.Command("lpstat", args...)
+	cmd.Env = append(os.Environ(), "LC_ALL=C", "LANG=C")
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("erro ao executar lpstat: %w", err)
+	}
+	timer := time.AfterFunc(cupsCommandTimeout, func() { cmd.Process.Kill() })
+	defer timer.Stop()
+	if err := cmd.Wait(); err != nil {
+		return "", fmt.Errorf("erro ao executar lpstat: %w | stderr: %s", err, stderr.String())
+	}
+	return out.String(), nil
+}
+
+// parseLPStatJobs interpreta a saída do lpstat -l -o: uma linha por job,
+// seguida das linhas de detalhe indentadas (Status, Alerts, queued for...)
+func parseLPStatJobs(output string) map[string]cupsJob {
+	jobs := map[string]cupsJob{}
+	var current *cupsJob
+	save := func() {
+		if current != nil {
+			jobs[current.ID] = *current
+		}
+	}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		text := strings.TrimSpace(line)
+		if text == "" {
+			continue
+		}
+		if line[0] != ' ' && line[0] != '\t' {
+			save()
+			current = &cupsJob{ID: strings.Fields(line)[0]}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(text, "Alerts:"):
+			current.Reasons = strings.Fields(strings.TrimPrefix(text, "Alerts:"))
+		case strings.HasPrefix(text, "Status:"):
+			current.Message = strings.TrimSpace(strings.TrimPrefix(text, "Status:"))
+		}
+	}
+	save()
+	return jobs
+}
+
+// cupsJobs lista os jobs da fila: ativos (pendentes, retidos ou imprimindo)
+// ou, com completed, os finalizados
+func cupsJobs(queue string, completed bool) (map[string]cupsJob, error) {
+	args := []string{"-l", "-o", queue}
+	if completed {
+		args = []string{"-l", "-W", "completed", "-o", queue}
+	}
+	out, err := lpstat(args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseLPStatJobs(out), nil
+}
+
+// getCUPSPrinterState consulta se a fila está habilitada e o motivo da parada
+func getCUPSPrinterState(queue string) (cupsPrinterState, error) {
+	out, err := lpstat("-p", queue)
+	if err != nil {
+		return cupsPrinterState{}, err
+	}
+	// printer EPSON is idle.  enabled since ...
+	// printer EPSON disabled since ... -
+	//	<motivo>
+	state := cupsPrinterState{Enabled: true}
+	lines := strings.Split(out, "\n")
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "printer ") {
+			continue
+		}
+		if strings.Contains(line, " disabled since ") {
+			state.Enabled = false
+			if i+1 < len(lines) {
+				state.Reason = strings.TrimSpace(lines[i+1])
+			}
+		}
+		break
+	}
+	return state, nil
+}
+
+// cupsSystemStatus retorna o status de uma impressora do sistema para o
+// get_printers detalhado (apenas CUPS)
+func cupsSystemStatus(name string) *PrinterStatus {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	s := &PrinterStatus{State: stateUnknown, CheckedAt: time.Now()}
+	state, err := getCUPSPrinterState(name)
+	switch {
+	case err != nil:
+		s.Error = err.Error()
+	case state.Enabled:
+		s.State = escpos.StateReady
+	default:
+		s.State, s.Error = StateStopped, state.Reason
+	}
+	return s
+}
+
+// trackedJob é um job do CUPS em acompanhamento
+type trackedJob struct {
+	job   Job
+	since time.Time
+}
+
+// cupsMonitor consulta a situação dos jobs enviados pelo lp até que terminem
+type cupsMonitor struct {
+	mu      sync.Mutex
+	jobs    map[string]*trackedJob // por id do job no CUPS
+	running bool
+	enabled map[string]time.Time // último cupsenable por fila
+}
+
+var cupsJobsMonitor = &cupsMonitor{jobs: map[string]*trackedJob{}, enabled: map[string]time.Time{}}
+
+// trackCUPSJob passa a acompanhar um job entregue ao CUPS
+func trackCUPSJob(job Job) {
+	if runtime.GOOS == "windows" || job.SystemJobID == "" || cupsQueue(job.SystemJobID) == "" {
+		return
+	}
+	m := cupsJobsMonitor
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs[job.SystemJobID] = &trackedJob{job: job, since: time.Now()}
+	if !m.running {
+		m.running = true
+		go m.run()
+	}
+}
+
+// run consulta as filas enquanto houver jobs em acompanhamento
+func (m *cupsMonitor) run() {
+	ticker := time.NewTicker(cupsPollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		m.mu.Lock()
+		queues := map[string][]*trackedJob{}
+		for id, t := range m.jobs {
+			if time.Since(t.since) > cupsTrackTimeout {
+				log.Printf("CUPS: Job %s (%s) ainda %s após %s; acompanhamento encerrado", t.job.ID, id, t.job.SystemState, cupsTrackTimeout)
+				delete(m.jobs, id)
+				continue
+			}
+			queue := cupsQueue(id)
+			queues[queue] = append(queues[queue], t)
+		}
+		if len(queues) == 0 {
+			m.running = false
+			m.mu.Unlock()
+			return
+		}
+		m.mu.Unlock()
+
+		for queue, tracked := range queues {
+			m.poll(queue, tracked)
+		}
+	}
+}
+
+// poll atualiza os jobs de uma fila
+func (m *cupsMonitor) poll(queue string, tracked []*trackedJob) {
+	active, err := cupsJobs(queue, false)
+	if err != nil {
+		log.Printf("CUPS: Erro ao consultar jobs de [%s]: %v", queue, err)
+		return
+	}
+
+	var finished map[string]cupsJob
+	var printer *cupsPrinterState
+	for _, t := range tracked {
+		id := t.job.SystemJobID
+		state, reason := "", ""
+
+		if cj, ok := active[id]; ok {
+			switch {
+			case cj.hasReason("job-hold"):
+				state, reason = CUPSHeld, cj.Message
+			default:
+				if printer == nil {
+					s, err := getCUPSPrinterState(queue)
+					if err != nil {
+						log.Printf("CUPS: Erro ao consultar a fila [%s]: %v", queue, err)
+						s = cupsPrinterState{Enabled: true}
+					}
+					printer = &s
+					if !s.Enabled {
+						m.enableQueue(queue, s.Reason)
+					}
+				}
+				switch {
+				case !printer.Enabled:
+					state, reason = CUPSStopped, printer.Reason
+				case cj.hasReason("job-printing"):
+					state = CUPSProcessing
+				default:
+					state = CUPSPending
+				}
+			}
+		} else {
+			if finished == nil {
+				if finished, err = cupsJobs(queue, true); err != nil {
+					log.Printf("CUPS: Erro ao consultar jobs finalizados de [%s]: %v", queue, err)
+					return
+				}
+			}
+			state, reason = finished[id].finalState()
+		}
+
+		m.update(t, state, reason)
+	}
+}
+
+// enableQueue reativa a fila parada quando auto_enable está ligado
+func (m *cupsMonitor) enableQueue(queue, reason string) {
+	if !printerSettings(queue).AutoEnable {
+		return
+	}
+	m.mu.Lock()
+	if time.Since(m.enabled[queue]) < cupsEnableInterval {
+		m.mu.Unlock()
+		return
+	}
+	m.enabled[queue] = time.Now()
+	m.mu.Unlock()
+
+	log.Printf("CUPS: Fila [%s] parada (%s); reativando com cupsenable", queue, reason)
+	if out, err := exec.Command("cupsenable", queue).CombinedOutput(); err != nil {
+		log.Printf("CUPS: Erro ao reativar a fila [%s]: %v | %s", queue, err, strings.TrimSpace(string(out)))
+	}
+}
+
+// update grava a nova situação do job e encerra o acompanhamento quando ele termina
+func (m *cupsMonitor) update(t *trackedJob, state, reason string) {
+	job := &t.job
+	if state == CUPSCompleted || state == CUPSCanceled || state == CUPSAborted {
+		m.mu.Lock()
+		delete(m.jobs, job.SystemJobID)
+		m.mu.Unlock()
+	}
+	if state == job.SystemState {
+		return
+	}
+
+	job.SystemState = state
+	job.Error = ""
+	switch state {
+	case CUPSCompleted:
+		job.Status = JobCompleted
+	case CUPSCanceled:
+		job.Status, job.Error = JobFailed, "job cancelado no CUPS"
+	case CUPSAborted:
+		job.Status, job.Error = JobFailed, "job abortado pelo CUPS"
+	case CUPSHeld:
+		job.Status, job.Error = JobHeld, "job retido na fila do CUPS"
+	case CUPSStopped:
+		job.Status, job.Error = JobHeld, "fila do CUPS parada"
+	default:
+		job.Status = JobSent
+	}
+	if reason != "" && job.Error != "" {
+		job.Error += ": " + reason
+	}
+	now := time.Now()
+	job.UpdatedAt = &now
+
+	log.Printf("CUPS: Job %s (%s) em [%s]: %s", job.ID, job.SystemJobID, job.Printer, state)
+	if err := updateJob(job); err != nil {
+		log.Printf("Jobs: Erro ao atualizar job %s: %v", job.ID, err)
+	}
+	broadcastEvent(Event{Event: EventJobUpdated, Data: *job})
+}
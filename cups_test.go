@@ -0,0 +1,121 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLPRequestID(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{"request id is EPSON-12 (1 file(s))\n", "EPSON-12"},
+		{"request id is Cozinha_TM-T20-7 (0 file(s))", "Cozinha_TM-T20-7"},
+		{"id de requisição é EPSON-3 (1 arquivo(s))\n", "EPSON-3"},
+		{"lp: The printer or class does not exist.\n", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := parseLPRequestID(tt.output); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestCUPSQueue(t *testing.T) {
+	tests := map[string]string{
+		"EPSON-12":         "EPSON",
+		"Cozinha-TM-T20-7": "Cozinha-TM-T20",
+		"12":               "",
+		"-12":              "",
+	}
+	for id, want := range tests {
+		if got := cupsQueue(id); got != want {
+			t.Errorf("%q: got %q, want %q", id, got, want)
+		}
+	}
+}
+
+// Saída do lpstat -l -o EPSON com LC_ALL=C
+const lpstatActive = `EPSON-12                root              1024   Mon 19 Oct 2026 10:00:01 AM -03
+	Status: Waiting for printer to finish.
+	Alerts: job-printing
+	queued for EPSON
+EPSON-13                caixa             2048   Mon 19 Oct 2026 10:00:05 AM -03
+	Alerts: job-incoming
+	queued for EPSON
+EPSON-14                caixa              512   Mon 19 Oct 2026 10:00:09 AM -03
+	Status: Job held for authentication.
+	Alerts: job-hold-until-specified job-incoming
+	queued for EPSON
+`
+
+// Saída do lpstat -l -W completed -o EPSON com LC_ALL=C
+const lpstatCompleted = `EPSON-9                 root              1024   Mon 19 Oct 2026 09:58:40 AM -03
+	Alerts: job-completed-successfully
+	queued for EPSON
+EPSON-10                root              1024   Mon 19 Oct 2026 09:59:02 AM -03
+	Status: Canceled by user.
+	Alerts: job-canceled-by-user
+	queued for EPSON
+EPSON-11                root              1024   Mon 19 Oct 2026 09:59:30 AM -03
+	Status: Filter failed
+	Alerts: job-aborted-by-system printer-stopped
+	queued for EPSON
+EPSON-15                root              1024   Mon 19 Oct 2026 10:01:12 AM -03
+	queued for EPSON
+`
+
+func TestParseLPStatJobs(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   map[string]cupsJob
+	}{
+		{"empty", "", map[string]cupsJob{}},
+		{"active", lpstatActive, map[string]cupsJob{
+			"EPSON-12": {ID: "EPSON-12", Reasons: []string{"job-printing"}, Message: "Waiting for printer to finish."},
+			"EPSON-13": {ID: "EPSON-13", Reasons: []string{"job-incoming"}},
+			"EPSON-14": {ID: "EPSON-14", Reasons: []string{"job-hold-until-specified", "job-incoming"}, Message: "Job held for authentication."},
+		}},
+		{"completed", lpstatCompleted, map[string]cupsJob{
+			"EPSON-9":  {ID: "EPSON-9", Reasons: []string{"job-completed-successfully"}},
+			"EPSON-10": {ID: "EPSON-10", Reasons: []string{"job-canceled-by-user"}, Message: "Canceled by user."},
+			"EPSON-11": {ID: "EPSON-11", Reasons: []string{"job-aborted-by-system", "printer-stopped"}, Message: "Filter failed"},
+			"EPSON-15": {ID: "EPSON-15"},
+		}},
+		{"stray detail line", "\tAlerts: job-printing\n", map[string]cupsJob{}},
+	}
+	for _, tt := range tests {
+		if got := parseLPStatJobs(tt.output); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCUPSJobFinalState(t *testing.T) {
+	jobs := parseLPStatJobs(lpstatCompleted)
+	tests := []struct {
+		id     string
+		state  string
+		reason string
+	}{
+		{"EPSON-9", CUPSCompleted, ""},
+		{"EPSON-10", CUPSCanceled, "Canceled by user."},
+		{"EPSON-11", CUPSAborted, "Filter failed"},
+		{"EPSON-15", CUPSCompleted, ""},
+		{"EPSON-99", CUPSCompleted, ""}, // fora do histórico
+	}
+	for _, tt := range tests {
+		state, reason := jobs[tt.id].finalState()
+		if state != tt.state || reason != tt.reason {
+			t.Errorf("%s: got %s/%q, want %s/%q", tt.id, state, reason, tt.state, tt.reason)
+		}
+	}
+
+	active := parseLPStatJobs(lpstatActive)
+	if !active["EPSON-14"].hasReason("job-hold") || active["EPSON-13"].hasReason("job-printing") {
+		t.Error("hasReason misread the Alerts line")
+	}
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/willjrcom/gfood-printer/internal/escpos"
+)
+
+const (
+	directPort         = "9100"
+	directDialTimeout  = 5 * time.Second
+	directWriteTimeout = 30 * time.Second
+	statusReplyTimeout = 2 * time.Second
+	jobConfirmTimeout  = 60 * time.Second
+)
+
+var (
+	errStatusTimeout = errors.New("impressora não respondeu ao pedido de status")
+	serialPortRe     = regexp.MustCompile(`(?i)^(\\\\\.\\)?COM[0-9]+$`)
+)
+
+// parsePrinterAddress separa o endereço de uma impressora direta em rede
+// ("tcp" com host:porta) ou dispositivo ("device" com o caminho). Aceita
+// tcp://host[:porta], file:///dev/..., caminhos absolutos e portas COM.
+func parsePrinterAddress(address string) (network, target string, err error) {
+	switch {
+	case strings.HasPrefix(address, "tcp://"):
+		host := strings.TrimPrefix(address, "tcp://")
+		if host == "" {
+			return "", "", fmt.Errorf("endereço sem host: %s", address)
+		}
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(host, directPort)
+		}
+		return "tcp", host, nil
+	case strings.HasPrefix(address, "file://"):
+		path := strings.TrimPrefix(address, "file://")
+		if path == "" {
+			return "", "", fmt.Errorf("endereço sem caminho: %s", address)
+		}
+		return "device", path, nil
+	case strings.HasPrefix(address, "/"), serialPortRe.MatchString(address):
+		return "device", address, nil
+	default:
+		return "", "", fmt.Errorf("endereço inválido: %s (use tcp://host:9100, /dev/usb/lp0 ou COM3)", address)
+	}
+}
+
+// printerConn é uma conexão bidirecional com a impressora
+type printerConn struct {
+	rw       io.ReadWriteCloser
+	deadline interface{ SetReadDeadline(time.Time) error }
+	writeTo  interface{ SetWriteDeadline(time.Time) error }
+	closed   bool // fechada por uma leitura sem deadline que expirou
+}
+
+// openPrinterConn abre a conexão TCP ou o dispositivo da impressora
+func openPrinterConn(address string) (*printerConn, error) {
+	network, target, err := parsePrinterAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	if network == "tcp" {
+		c, err := net.DialTimeout("tcp", target, directDialTimeout)
+		if err != nil {
+			return nil, err
+		}
+		return &printerConn{rw: c, deadline: c, writeTo: c}, nil
+	}
+	f, err := os.OpenFile(target, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &printerConn{rw: f, deadline: f, writeTo: f}, nil
+}
+
+func (c *printerConn) Close() error {
+	return c.rw.Close()
+}
+
+func (c *printerConn) write(data []byte) error {
+	if c.writeTo != nil {
+		c.writeTo.SetWriteDeadline(time.Now().Add(directWriteTimeout))
+	}
+	_, err := c.rw.Write(data)
+	return err
+}
+
+// readByte lê um byte de resposta. Dispositivos sem suporte a deadline (ex.:
+// /dev/usb/lp0) são lidos em paralelo e fechados quando o tempo acaba.
+func (c *printerConn) readByte(timeout time.Duration) (byte, error) {
+	buf := make([]byte, 1)
+	if c.deadline != nil && c.deadline.SetReadDeadline(time.Now().Add(timeout)) == nil {
+		if _, err := io.ReadFull(c.rw, buf); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() || errors.Is(err, os.ErrDeadlineExceeded) {
+				return 0, errStatusTimeout
+			}
+			return 0, err
+		}
+		return buf[0], nil
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.ReadFull(c.rw, buf)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return buf[0], err
+	case <-time.After(timeout):
+		c.closed = true
+		c.rw.Close()
+		return 0, errStatusTimeout
+	}
+}
+
+// status consulta o status em tempo real (DLE EOT 1 a 4)
+func (c *printerConn) status() (escpos.Status, error) {
+	var answers [4]byte
+	for i, n := range []byte{escpos.StatusPrinter, escpos.StatusOfflineCause, escpos.StatusErrorCause, escpos.StatusPaperSensor} {
+		if err := c.write(escpos.StatusRequest(n)); err != nil {
+			return escpos.Status{}, err
+		}
+		b, err := c.readByte(statusReplyTimeout)
+		if err != nil {
+			return escpos.Status{}, err
+		}
+		answers[i] = b
+	}
+	return escpos.ParseStatus(answers[0], answers[1], answers[2], answers[3])
+}
+
+// PrinterStatus é o último status conhecido de uma impressora
+type PrinterStatus struct {
+	State     string         `json:"state"` // ready, paper_near_end, paper_out, cover_open, cutter_error, error, offline ou unknown
+	Status    *escpos.Status `json:"status,omitempty"`
+	Error     string         `json:"error,omitempty"`
+	CheckedAt time.Time      `json:"checked_at"`
+}
+
+const stateUnknown = "unknown"
+
+var (
+	printerStatusMu sync.Mutex
+	printerStatuses = map[string]PrinterStatus{}
+
+	// Uma conexão por vez com cada impressora direta: a porta 9100 e os
+	// dispositivos não aceitam acessos simultâneos
+	directLocksMu sync.Mutex
+	directLocks   = map[string]*sync.Mutex{}
+
+	// Impressoras que respondem ao DLE EOT mas não ao GS r: a confirmação do
+	// job passa a ser só a consulta de status, sem esperar jobConfirmTimeout
+	noPaperStatus sync.Map
+)
+
+func directLock(name string) *sync.Mutex {
+	directLocksMu.Lock()
+	defer directLocksMu.Unlock()
+	mu, ok := directLocks[name]
+	if !ok {
+		mu = &sync.Mutex{}
+		directLocks[name] = mu
+	}
+	return mu
+}
+
+// recordPrinterStatus guarda o resultado da última consulta à impressora
+func recordPrinterStatus(name string, status *escpos.Status, err error) PrinterStatus {
+	s := PrinterStatus{State: stateUnknown, Status: status, CheckedAt: time.Now()}
+	switch {
+	case err != nil:
+		s.State = escpos.StateOffline
+		s.Error = err.Error()
+	case status != nil:
+		s.State = status.State()
+	}
+
+	printerStatusMu.Lock()
+	printerStatuses[name] = s
+	printerStatusMu.Unlock()
+	return s
+}
+
+func lastPrinterStatus(name string) (PrinterStatus, bool) {
+	printerStatusMu.Lock()
+	defer printerStatusMu.Unlock()
+	s, ok := printerStatuses[name]
+	return s, ok
+}
+
+// statusMessages descreve os estados nos erros dos jobs
+var statusMessages = map[string]string{
+	escpos.StateOffline:     "offline",
+	escpos.StateCoverOpen:   "tampa aberta",
+	escpos.StatePaperOut:    "sem papel",
+	escpos.StateCutterError: "erro na guilhotina",
+	escpos.StateError:       "erro na impressora",
+}
+
+func printerStatusError(name string, status escpos.Status) error {
+	if status.Err() == nil {
+		return nil
+	}
+	return fmt.Errorf("impressora %s: %s", name, statusMessages[status.State()])
+}
+
+// directPrinter retorna a configuração da impressora ligada diretamente ao
+// agente (TCP, serial ou USB), sem passar pelo spooler do sistema
+func directPrinter(name string) (PrinterConfig, bool) {
+	if GlobalConfig == nil || name == "*" {
+		return PrinterConfig{}, false
+	}
+	p, ok := GlobalConfig.Printers[name]
+	return p, ok && p.Address != ""
+}
+
+// directPrinterNames lista as impressoras diretas configuradas
+func directPrinterNames() []string {
+	if GlobalConfig == nil {
+		return nil
+	}
+	names := []string{}
+	for name, p := range GlobalConfig.Printers {
+		if name != "*" && p.Address != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// pollPrinterStatus consulta o status de uma impressora direta. Se ela estiver
+// imprimindo, retorna o último status conhecido.
+func pollPrinterStatus(name string, p PrinterConfig) PrinterStatus {
+	lock := directLock(name)
+	if !lock.TryLock() {
+		if s, ok := lastPrinterStatus(name); ok {
+			return s
+		}
+		return PrinterStatus{State: stateUnknown}
+	}
+	defer lock.Unlock()
+
+	conn, err := openPrinterConn(p.Address)
+	if err != nil {
+		return recordPrinterStatus(name, nil, err)
+	}
+	defer conn.Close()
+
+	status, err := conn.status()
+	if err != nil {
+		return recordPrinterStatus(name, nil, err)
+	}
+	return recordPrinterStatus(name, &status, nil)
+}
+
+// printDirect envia o job para a impressora direta. Com check_status, consulta
+// o status antes de enviar (falha sem imprimir se houver erro) e, depois dos
+// dados, aguarda a resposta do GS r 1 para confirmar que o job foi impresso:
+// sem papel ou com a tampa aberta a impressora para e não responde.
+func printDirect(job *Job, p PrinterConfig, data []byte) error {
+	lock := directLock(job.Printer)
+	lock.Lock()
+	defer lock.Unlock()
+
+	log.Printf("Imprimindo em [%s] (direto: %s)", job.Printer, p.Address)
+	conn, err := openPrinterConn(p.Address)
+	if err != nil {
+		recordPrinterStatus(job.Printer, nil, err)
+		return fmt.Errorf("impressora %s inacessível: %v", job.Printer, err)
+	}
+	defer conn.Close()
+
+	check := p.statusCheck()
+	if check {
+		status, err := conn.status()
+		if err != nil {
+			// Impressoras sem canal de retorno: imprime sem verificação
+			log.Printf("Impressora [%s]: sem resposta de status (%v); imprimindo sem verificação", job.Printer, err)
+			check = false
+			if conn.closed {
+				if conn, err = openPrinterConn(p.Address); err != nil {
+					return fmt.Errorf("impressora %s inacessível: %v", job.Printer, err)
+				}
+				defer conn.Close()
+			}
+		} else {
+			recordPrinterStatus(job.Printer, &status, nil)
+			if err := printerStatusError(job.Printer, status); err != nil {
+				return err
+			}
+			if status.PaperNearEnd {
+				log.Printf("Impressora [%s]: pouco papel", job.Printer)
+			}
+		}
+	}
+
+	if err := conn.write(data); err != nil {
+		recordPrinterStatus(job.Printer, nil, err)
+		return fmt.Errorf("erro ao enviar para a impressora %s: %v", job.Printer, err)
+	}
+	if !check {
+		return nil
+	}
+
+	if _, ok := noPaperStatus.Load(job.Printer); ok {
+		return confirmByStatus(job, p, conn)
+	}
+
+	// GS r 1 é processado depois dos dados: a resposta confirma a impressão
+	if err := conn.write(escpos.PaperStatusRequest()); err != nil {
+		return fmt.Errorf("erro ao confirmar impressão em %s: %v", job.Printer, err)
+	}
+	paper, err := conn.readByte(jobConfirmTimeout)
+	if err != nil {
+		// Sem resposta: ou a impressora parou no meio do job (papel, tampa) ou
+		// só não responde ao GS r
+		log.Printf("Impressora [%s]: sem resposta ao GS r (%v); confirmando o job %s pelo status", job.Printer, err, job.ID)
+		if err := confirmByStatus(job, p, conn); err != nil {
+			return err
+		}
+		log.Printf("Impressora [%s]: job %s dado como impresso; os próximos são confirmados pelo status", job.Printer, job.ID)
+		noPaperStatus.Store(job.Printer, true)
+		return nil
+	}
+
+	// Com a resposta o job já saiu; o status depois dele fica para o próximo
+	if status, err := conn.status(); err == nil {
+		recordPrinterStatus(job.Printer, &status, nil)
+		if status.State() != escpos.StateReady {
+			log.Printf("Impressora [%s]: %s após o job %s", job.Printer, status.State(), job.ID)
+		}
+	} else if nearEnd, _ := escpos.ParsePaperStatus(paper); nearEnd {
+		log.Printf("Impressora [%s]: pouco papel", job.Printer)
+	}
+	return nil
+}
+
+// confirmByStatus confirma pelo status o job já enviado, nas impressoras que
+// não respondem ao GS r. Os dados já foram enviados, então só falha (e
+// reimprime no retry) quando o status mostra um defeito. Dispositivos sem
+// deadline (ex.: /dev/usb/lp0) são fechados pela leitura que expirou: a
+// consulta usa uma nova conexão e, sem resposta, o job é dado como impresso.
+func confirmByStatus(job *Job, p PrinterConfig, conn *printerConn) error {
+	if conn.closed {
+		reopened, err := openPrinterConn(p.Address)
+		if err != nil {
+			log.Printf("Impressora [%s]: erro ao reabrir o dispositivo (%v); job %s dado como impresso", job.Printer, err, job.ID)
+			return nil
+		}
+		defer reopened.Close()
+		conn = reopened
+	}
+
+	status, err := conn.status()
+	if err != nil {
+		if conn.closed {
+			log.Printf("Impressora [%s]: dispositivo sem resposta após o job (%v); job %s dado como impresso", job.Printer, err, job.ID)
+			return nil
+		}
+		return fmt.Errorf("impressora %s não confirmou a impressão: %v", job.Printer, err)
+	}
+	recordPrinterStatus(job.Printer, &status, nil)
+	return printerStatusError(job.Printer, status)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/willjrcom/gfood-printer/internal/escpos"
+)
+
+// Limites do pulso da gaveta (ESC p m t1 t2, em unidades de 2ms)
+const maxDrawerPulseMs = 510
+
+// DrawerRequest pede a abertura da gaveta ligada a uma impressora. Pin e os
+// tempos, quando omitidos, vêm da configuração da impressora.
+type DrawerRequest struct {
+	Printer string `json:"printer,omitempty"` // impressora ou estação; vazio usa drawer_printer
+	Pin     *int   `json:"pin,omitempty"`
+	OnMs    int    `json:"on_ms,omitempty"`
+	OffMs   int    `json:"off_ms,omitempty"`
+	User    string `json:"user,omitempty"` // quem diz ter pedido; vai para a auditoria como claimed_user
+}
+
+// validateDrawerPulse verifica o pino e os tempos do pulso da gaveta
+func validateDrawerPulse(pin, onMs, offMs int) error {
+	if pin != 0 && pin != 1 {
+		return fmt.Errorf("drawer_pin inválido: %d (use 0 para o pino 2 ou 1 para o pino 5)", pin)
+	}
+	if onMs < 0 || onMs > maxDrawerPulseMs || offMs < 0 || offMs > maxDrawerPulseMs {
+		return fmt.Errorf("tempos do pulso da gaveta devem estar entre 0 e %d ms", maxDrawerPulseMs)
+	}
+	return nil
+}
+
+// drawerPrinter resolve a impressora da gaveta. Diferente da impressão, não
+// cai na impressora padrão quando a pedida não existe: abrir a gaveta de
+// outro caixa seria pior que não abrir.
+func drawerPrinter(requested string) (string, error) {
+	if requested == "" && GlobalConfig != nil {
+		requested = GlobalConfig.DrawerPrinter
+	}
+	return exactPrinter(requested)
+}
+
+// openDrawer envia o pulso da gaveta (ESC p) à impressora e registra na
+// auditoria quem pediu. caller é o chamador autenticado pelo token.
+func openDrawer(req DrawerRequest, caller, source, remote string) (*Job, error) {
+	entry := AuditEntry{Action: "open_drawer", User: caller, Claimed: req.User, Source: source, Remote: remote, Requested: req.Printer}
+
+	job, err := kickDrawer(req, source)
+	if job != nil {
+		entry.Printer, entry.JobID = job.Printer, job.ID
+	}
+	entry.Status = "ok"
+	if err != nil {
+		entry.Status, entry.Error = "error", err.Error()
+	}
+	recordAudit(entry)
+	return job, err
+}
+
+func kickDrawer(req DrawerRequest, source string) (*Job, error) {
+	printer, err := drawerPrinter(req.Printer)
+	if err != nil {
+		return nil, err
+	}
+
+	pin, onMs, offMs := printerSettings(printer).drawerPulse()
+	if req.Pin != nil {
+		pin = *req.Pin
+	}
+	if req.OnMs > 0 {
+		onMs = req.OnMs
+	}
+	if req.OffMs > 0 {
+		offMs = req.OffMs
+	}
+	if err := validateDrawerPulse(pin, onMs, offMs); err != nil {
+		return nil, err
+	}
+
+	var buf escpos.Buffer
+	buf.DrawerKick(pin, onMs, offMs)
+	job := &Job{Printer: printer, Requested: req.Printer, Source: source}
+	return job, printJob(job, buf.Bytes())
+}
+
+// drawerCaller identifica quem chama o HTTP pelo token do cabeçalho
+// Authorization: Bearer
+func drawerCaller(r *http.Request) (string, bool) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == r.Header.Get("Authorization") {
+		return "", false
+	}
+	return drawerTokenCaller(token)
+}
+
+// drawerTokenCaller identifica o chamador pelo token: vale o access_token
+// (chamador "backend") ou um dos drawer_tokens, que dão o nome do chamador
+// na auditoria
+func drawerTokenCaller(token string) (string, bool) {
+	if GlobalConfig == nil || token == "" {
+		return "", false
+	}
+	if secret := GlobalConfig.GetAccessToken(); secret != "" && subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1 {
+		return "backend", true
+	}
+	for name, secret := range GlobalConfig.DrawerTokens {
+		if secret != "" && subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1 {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func writeDrawerResponse(w http.ResponseWriter, status int, resp Response) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// drawerHandler atende POST /drawer/open com o mesmo corpo do open_drawer. O
+// pedido precisa do token e de Content-Type application/json: assim uma
+// página aberta no navegador do caixa não consegue disparar a gaveta com um
+// POST simples (sem preflight).
+func drawerHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeDrawerResponse(w, http.StatusMethodNotAllowed, Response{Status: "error", Message: "Use POST"})
+		return
+	}
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		writeDrawerResponse(w, http.StatusUnsupportedMediaType, Response{Status: "error", Message: "Content-Type deve ser application/json"})
+		return
+	}
+	caller, ok := drawerCaller(r)
+	if !ok {
+		log.Printf("HTTP: Abertura da gaveta recusada para %s: token ausente ou inválido", r.RemoteAddr)
+		recordAudit(AuditEntry{Action: "open_drawer", Source: SourceHTTP, Remote: r.RemoteAddr, Status: "error", Error: "não autorizado"})
+		writeDrawerResponse(w, http.StatusUnauthorized, Response{Status: "error", Message: "Não autorizado"})
+		return
+	}
+
+	var req DrawerRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req); err != nil {
+		writeDrawerResponse(w, http.StatusBadRequest, Response{Status: "error", Message: "Corpo inválido (esperado JSON)"})
+		return
+	}
+
+	job, err := openDrawer(req, caller, SourceHTTP, r.RemoteAddr)
+	if err != nil {
+		log.Printf("HTTP: Erro ao abrir a gaveta: %v", err)
+		writeDrawerResponse(w, http.StatusBadGateway, Response{Status: "error", Message: err.Error()})
+		return
+	}
+	writeDrawerResponse(w, http.StatusOK, Response{Status: "ok", Message: "Gaveta aberta", Data: map[string]string{"job_id": job.ID, "printer": job.Printer}})
+}
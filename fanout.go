@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// maxCopies limita as cópias de um destino
+const maxCopies = 20
+
+// Situação de cada destino de uma impressão
+const (
+	TargetPrinted = "printed"
+	TargetFailed  = "failed"
+)
+
+// PrintTarget é um destino (impressora, estação ou grupo) com o número de
+// cópias. No JSON aceita o nome direto ("cozinha") ou o objeto.
+type PrintTarget struct {
+	Printer string `json:"printer"`
+	Copies  int    `json:"copies,omitempty"`
+
+	render renderFunc // conteúdo próprio do destino (ex.: ticket parcial do roteamento)
+	alert  bool       // acrescenta o aviso sonoro da estação
+}
+
+func (t *PrintTarget) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*t = PrintTarget{Printer: name}
+		return nil
+	}
+	type target PrintTarget
+	return json.Unmarshal(data, (*target)(t))
+}
+
+// TargetResult é a situação da impressão em um destino
+type TargetResult struct {
+	Target   string   `json:"target"`
+	Copies   int      `json:"copies"`
+	Status   string   `json:"status"` // printed ou failed
+	Error    string   `json:"error,omitempty"`
+	JobID    string   `json:"job_id,omitempty"`
+	Printer  string   `json:"printer,omitempty"`  // impressora que imprimiu (ou a última tentada)
+	Printers []string `json:"printers,omitempty"` // grupos broadcast: todas as que imprimiram
+
+	jobs []*Job
+}
+
+// normalizeTargets monta a lista de destinos: os informados em targets ou,
+// sem eles, a impressora pedida. copies vale para os destinos sem cópias.
+func normalizeTargets(printer string, copies int, targets []PrintTarget) ([]PrintTarget, error) {
+	if copies < 0 || copies > maxCopies {
+		return nil, fmt.Errorf("copies deve estar entre 1 e %d", maxCopies)
+	}
+	if len(targets) == 0 {
+		targets = []PrintTarget{{Printer: printer}}
+	}
+
+	result := make([]PrintTarget, len(targets))
+	for i, t := range targets {
+		if t.Copies == 0 {
+			t.Copies = copies
+		}
+		if t.Copies == 0 {
+			t.Copies = 1
+		}
+		if t.Copies < 0 || t.Copies > maxCopies {
+			return nil, fmt.Errorf("destino %s: copies deve estar entre 1 e %d", t.Printer, maxCopies)
+		}
+		if len(targets) > 1 && t.Printer == "" {
+			return nil, fmt.Errorf("destino %d sem impressora", i+1)
+		}
+		result[i] = t
+	}
+	return result, nil
+}
+
+// withCopies repete o conteúdo renderizado; cada cópia termina no próprio corte
+func withCopies(render renderFunc, copies int) renderFunc {
+	if copies <= 1 {
+		return render
+	}
+	return func(printerName string) ([]byte, error) {
+		data, err := render(printerName)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.Repeat(data, copies), nil
+	}
+}
+
+// dispatchTargets imprime em cada destino e retorna a situação de cada um.
+// Destinos presentes em done (já impressos numa tentativa anterior da mesma
+// mensagem) não são impressos de novo. Erros de conteúdo interrompem a
+// impressão; falhas de impressão são reunidas no erro retornado.
+func dispatchTargets(targets []PrintTarget, base Job, render renderFunc, done map[int]TargetResult) ([]TargetResult, error) {
+	results := make([]TargetResult, len(targets))
+	var failures []string
+	var lastErr error
+	for i, t := range targets {
+		if r, ok := done[i]; ok {
+			results[i] = r
+			continue
+		}
+
+		job := base
+		if t.Copies > 1 {
+			job.Copies = t.Copies
+		}
+		targetRender := render
+		if t.render != nil {
+			targetRender = t.render
+		}
+		targetRender = withCopies(targetRender, t.Copies)
+		if t.alert {
+			targetRender = withAlert(targetRender, t.Printer)
+		}
+		jobs, err := dispatchPrint(t.Printer, job, targetRender)
+		r := TargetResult{Target: t.Printer, Copies: t.Copies, Status: TargetPrinted, jobs: jobs}
+		if printed := printedJob(jobs); printed != nil {
+			r.JobID, r.Printer = printed.ID, printed.Printer
+		}
+		if len(jobs) > 1 {
+			for _, j := range jobs {
+				if j.Status != JobFailed {
+					r.Printers = append(r.Printers, j.Printer)
+				}
+			}
+		}
+		if err != nil {
+			r.Status, r.Error = TargetFailed, err.Error()
+			if isContentError(err) {
+				results[i] = r
+				return results[:i+1], err
+			}
+			failures = append(failures, fmt.Sprintf("%s: %v", targetLabel(t.Printer), err))
+			lastErr = err
+		}
+		results[i] = r
+	}
+
+	if len(failures) == 0 {
+		return results, nil
+	}
+	if len(targets) == 1 {
+		return results, lastErr
+	}
+	return results, fmt.Errorf("falha em %d de %d destinos: %s", len(failures), len(targets), strings.Join(failures, "; "))
+}
+
+func targetLabel(printer string) string {
+	if printer == "" {
+		return "default"
+	}
+	return printer
+}
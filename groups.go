@@ -0,0 +1,285 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Políticas dos grupos de impressoras
+const (
+	PolicyStrict    = "strict"    // só a primeira impressora; se falhar, o job falha
+	PolicyFailover  = "failover"  // tenta as impressoras na ordem até uma imprimir
+	PolicyBroadcast = "broadcast" // imprime em todas
+)
+
+// EventPrinterFailover avisa os navegadores quando a impressão não saiu na
+// impressora pedida (failover do grupo ou impressora não encontrada)
+const EventPrinterFailover = "printer_failover"
+
+// PrinterGroup é uma lista ordenada de impressoras atendida como um destino só
+type PrinterGroup struct {
+	Printers []string `json:"printers"`
+	Policy   string   `json:"policy,omitempty"` // failover (padrão), strict ou broadcast
+}
+
+// policy retorna a política do grupo com o padrão aplicado
+func (g PrinterGroup) policy() string {
+	if g.Policy == "" {
+		return PolicyFailover
+	}
+	return g.Policy
+}
+
+// validateGroup verifica o nome, a política e as impressoras do grupo
+func validateGroup(c *Config, name string, g PrinterGroup) error {
+	if !stationNameRe.MatchString(name) || name == "default" {
+		return fmt.Errorf("nome de grupo inválido: %q (use letras minúsculas, números, - e _)", name)
+	}
+	if _, ok := c.Stations[name]; ok {
+		return fmt.Errorf("grupo %s tem o nome de uma estação", name)
+	}
+	switch g.Policy {
+	case "", PolicyStrict, PolicyFailover, PolicyBroadcast:
+	default:
+		return fmt.Errorf("grupo %s: política inválida: %s (use strict, failover ou broadcast)", name, g.Policy)
+	}
+	if len(g.Printers) == 0 {
+		return fmt.Errorf("grupo %s sem impressoras", name)
+	}
+	for _, p := range g.Printers {
+		if _, ok := c.Groups[p]; ok {
+			return fmt.Errorf("grupo %s contém outro grupo (%s)", name, p)
+		}
+		if _, ok := c.Stations[p]; ok {
+			return fmt.Errorf("grupo %s contém uma estação (%s)", name, p)
+		}
+	}
+	return nil
+}
+
+// JobAttempt é uma impressora do grupo que não imprimiu o job
+type JobAttempt struct {
+	Printer string `json:"printer"`
+	Error   string `json:"error"`
+}
+
+// FailoverEvent é o conteúdo do evento printer_failover
+type FailoverEvent struct {
+	Requested string       `json:"requested"`
+	Printer   string       `json:"printer,omitempty"` // impressora que imprimiu, vazia quando nenhuma
+	JobID     string       `json:"job_id,omitempty"`
+	Failed    []JobAttempt `json:"failed"`
+}
+
+// printTarget é o destino de uma impressão: uma impressora ou um grupo
+type printTarget struct {
+	Requested string   // nome pedido (impressora, estação ou grupo)
+	Group     string   // grupo resolvido, vazio para impressora avulsa
+	Policy    string   // política do grupo
+	Printers  []string // impressoras do grupo ou a impressora avulsa
+}
+
+// resolveTarget traduz estações e grupos para as impressoras do destino
+func resolveTarget(requested string) printTarget {
+	target := printTarget{Requested: requested}
+	name := requested
+	if printer, ok := stationPrinter(name); ok {
+		name = printer
+	}
+	if GlobalConfig != nil {
+		if g, ok := GlobalConfig.Groups[name]; ok {
+			target.Group, target.Policy, target.Printers = name, g.policy(), g.Printers
+			return target
+		}
+	}
+	target.Printers = []string{name}
+	return target
+}
+
+// primaryPrinter retorna a impressora que receberia o destino em condições
+// normais (usada na prévia)
+func primaryPrinter(requested string) string {
+	return resolvePrinterName(resolveTarget(requested).Printers[0])
+}
+
+// printerAvailable indica se a impressora está na lista de get_printers
+func printerAvailable(name string) bool {
+	printers, err := getPrinters()
+	if err != nil {
+		return false
+	}
+	for _, p := range printers {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// exactPrinter resolve a estação (ou a primeira impressora do grupo) sem
+// recorrer à impressora padrão quando a pedida não existe; vazio ou default
+// usam a padrão
+func exactPrinter(requested string) (string, error) {
+	printer := resolveTarget(requested).Printers[0]
+	if printer == "" || printer == "default" {
+		if printer = resolvePrinterName(printer); printer == "" {
+			return "", fmt.Errorf("nenhuma impressora disponível")
+		}
+		return printer, nil
+	}
+	if !printerAvailable(printer) {
+		return "", fmt.Errorf("impressora %s não encontrada", printer)
+	}
+	return printer, nil
+}
+
+// contentError marca erros do conteúdo (template, documento), que não mudam
+// com nova tentativa nem com outra impressora
+type contentError struct {
+	err error
+}
+
+func (e *contentError) Error() string { return e.err.Error() }
+func (e *contentError) Unwrap() error { return e.err }
+
+func isContentError(err error) bool {
+	var ce *contentError
+	return errors.As(err, &ce)
+}
+
+// renderFunc gera os bytes do job na largura e tabela de caracteres da impressora
+type renderFunc func(printerName string) ([]byte, error)
+
+// dispatchPrint imprime no destino pedido aplicando a política do grupo e
+// retorna os jobs gravados, com a impressora que de fato recebeu cada um
+func dispatchPrint(requested string, base Job, render renderFunc) ([]*Job, error) {
+	target := resolveTarget(requested)
+	base.Requested = requested
+
+	if target.Group == "" {
+		printer := resolvePrinterName(target.Printers[0])
+		var failed []JobAttempt
+		if want := target.Printers[0]; want != "" && want != "default" && printer != want {
+			failed = []JobAttempt{{Printer: want, Error: "impressora não encontrada"}}
+		}
+		job, err := printOn(base, printer, failed, render)
+		if err == nil && failed != nil {
+			notifyFailover(requested, job, failed)
+		}
+		if job == nil {
+			return nil, err
+		}
+		return []*Job{job}, err
+	}
+
+	switch target.Policy {
+	case PolicyStrict:
+		printer := target.Printers[0]
+		if !printerAvailable(printer) {
+			return nil, fmt.Errorf("impressora %s do grupo %s não encontrada", printer, target.Group)
+		}
+		job, err := printOn(base, printer, nil, render)
+		if job == nil {
+			return nil, err
+		}
+		return []*Job{job}, err
+
+	case PolicyBroadcast:
+		var jobs []*Job
+		var failed []JobAttempt
+		for _, printer := range target.Printers {
+			if !printerAvailable(printer) {
+				failed = append(failed, JobAttempt{Printer: printer, Error: "impressora não encontrada"})
+				continue
+			}
+			job, err := printOn(base, printer, nil, render)
+			if isContentError(err) {
+				return jobs, err
+			}
+			if err != nil {
+				failed = append(failed, JobAttempt{Printer: printer, Error: err.Error()})
+			}
+			jobs = append(jobs, job)
+		}
+		if len(failed) == len(target.Printers) {
+			return jobs, fmt.Errorf("nenhuma impressora do grupo %s imprimiu: %s", target.Group, describeAttempts(failed))
+		}
+		if len(failed) > 0 {
+			// Parte do grupo imprimiu: não reenvia para não duplicar nas demais
+			log.Printf("Grupo [%s]: falha em %d de %d impressoras: %s", target.Group, len(failed), len(target.Printers), describeAttempts(failed))
+			notifyFailover(requested, nil, failed)
+		}
+		return jobs, nil
+
+	default: // failover
+		var jobs []*Job
+		var failed []JobAttempt
+		for _, printer := range target.Printers {
+			if !printerAvailable(printer) {
+				failed = append(failed, JobAttempt{Printer: printer, Error: "impressora não encontrada"})
+				continue
+			}
+			job, err := printOn(base, printer, failed, render)
+			if isContentError(err) {
+				return jobs, err
+			}
+			jobs = append(jobs, job)
+			if err == nil {
+				if len(failed) > 0 {
+					notifyFailover(requested, job, failed)
+				}
+				return jobs, nil
+			}
+			failed = append(failed, JobAttempt{Printer: printer, Error: err.Error()})
+		}
+		notifyFailover(requested, nil, failed)
+		return jobs, fmt.Errorf("nenhuma impressora do grupo %s imprimiu: %s", target.Group, describeAttempts(failed))
+	}
+}
+
+// printedJob retorna o primeiro job que imprimiu ou, se todos falharam, o
+// último tentado
+func printedJob(jobs []*Job) *Job {
+	for _, job := range jobs {
+		if job.Status != JobFailed {
+			return job
+		}
+	}
+	if len(jobs) == 0 {
+		return nil
+	}
+	return jobs[len(jobs)-1]
+}
+
+// printOn renderiza e imprime o job em uma impressora. Erros de renderização
+// voltam como contentError, sem job gravado.
+func printOn(base Job, printer string, failed []JobAttempt, render renderFunc) (*Job, error) {
+	rendered, err := render(printer)
+	if err != nil {
+		return nil, &contentError{err}
+	}
+	job := base
+	job.Printer = printer
+	job.Failover = failed
+	return &job, printJob(&job, rendered)
+}
+
+// notifyFailover registra e avisa que a impressão não saiu onde foi pedida
+func notifyFailover(requested string, job *Job, failed []JobAttempt) {
+	event := FailoverEvent{Requested: requested, Failed: failed}
+	if job != nil {
+		event.Printer, event.JobID = job.Printer, job.ID
+		log.Printf("Failover: [%s] impresso em [%s] (%s)", requested, job.Printer, describeAttempts(failed))
+	}
+	broadcastEvent(Event{Event: EventPrinterFailover, Data: event})
+}
+
+func describeAttempts(attempts []JobAttempt) string {
+	parts := make([]string, len(attempts))
+	for i, a := range attempts {
+		parts[i] = fmt.Sprintf("%s: %s", a.Printer, a.Error)
+	}
+	return strings.Join(parts, "; ")
+}
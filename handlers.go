@@ -1,24 +1,18 @@
 package main
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/websocket"
+	"github.com/willjrcom/gfood-printer/internal/escpos"
+	"github.com/willjrcom/gfood-printer/internal/receipt"
 )
 
-type Config struct {
-	AccessToken string `json:"access_token"`
-	SchemaName  string `json:"schema_name"`
-	BackendURL  string `json:"backend_url"`
-	RabbitMQURL string `json:"rabbitmq_url"`
-}
-
-func (c *Config) GetAccessToken() string { return c.AccessToken }
-func (c *Config) GetSchemaName() string  { return c.SchemaName }
-func (c *Config) GetBackendURL() string  { return c.BackendURL }
-
 var (
 	GlobalConfig *Config
 	upgrader     = websocket.Upgrader{
@@ -39,22 +33,51 @@ type Response struct {
 }
 
 func wsHandler(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	rawConn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("Erro ao fazer upgrade para WebSocket: %v", err)
 		return
 	}
-	defer conn.Close()
+	defer rawConn.Close()
+
+	conn := registerWSClient(rawConn)
+	defer unregisterWSClient(conn)
 
 	log.Printf("Nova conexão WebSocket estabelecida de: %s", r.RemoteAddr)
 
+	// Avisa o navegador recém-conectado se o token estiver expirado
+	if tokens.Expired() {
+		conn.WriteJSON(Event{Event: EventTokenExpired})
+	}
+
+	// Impressão aguardando o conteúdo em um frame binário
+	var pendingBinary *PrintRequest
+
 	for {
-		var req Request
-		if err := conn.ReadJSON(&req); err != nil {
-			log.Printf("Conexão fechada ou erro ao ler JSON de %s: %v", r.RemoteAddr, err)
+		msgType, payload, err := conn.ReadMessage()
+		if err != nil {
+			log.Printf("Conexão fechada ou erro ao ler mensagem de %s: %v", r.RemoteAddr, err)
 			return
 		}
 
+		if msgType == websocket.BinaryMessage {
+			if pendingBinary == nil {
+				conn.WriteJSON(Response{Status: "error", Message: "Frame binário inesperado: envie antes a ação 'print' com binary=true"})
+				continue
+			}
+			printReq := *pendingBinary
+			pendingBinary = nil
+			printFromWS(conn, printReq, payload, printReq.contentType())
+			continue
+		}
+
+		var req Request
+		if err := json.Unmarshal(payload, &req); err != nil {
+			log.Printf("WebSocket: JSON inválido de %s: %v", r.RemoteAddr, err)
+			conn.WriteJSON(Response{Status: "error", Message: "JSON inválido"})
+			continue
+		}
+
 		log.Printf("WebSocket: Ação recebida [%s] de %s", req.Action, r.RemoteAddr)
 
 		switch req.Action {
@@ -63,74 +86,482 @@ func wsHandler(w http.ResponseWriter, r *http.Request) {
 			conn.WriteJSON(Response{Status: "ok", Message: "pong"})
 
 		case "get_printers":
-			printers, err := getPrinters()
+			// {"detailed": true} inclui tipo, perfil e status de cada impressora
+			var opts struct {
+				Detailed bool `json:"detailed"`
+			}
+			decodeData(req.Data, &opts)
+			var printers interface{}
+			var count int
+			var err error
+			if opts.Detailed {
+				var infos []PrinterInfo
+				infos, err = getPrintersDetailed()
+				printers, count = infos, len(infos)
+			} else {
+				var names []string
+				names, err = getPrinters()
+				printers, count = names, len(names)
+			}
 			if err != nil {
 				log.Printf("WebSocket: Erro ao listar impressoras: %v", err)
 				conn.WriteJSON(Response{Status: "error", Message: err.Error()})
 				continue
 			}
-			log.Printf("WebSocket: %d impressoras listadas para %s", len(printers), r.RemoteAddr)
+			log.Printf("WebSocket: %d impressoras listadas para %s", count, r.RemoteAddr)
 			conn.WriteJSON(Response{Status: "ok", Data: printers})
 
 		case "print":
-			// Validar Data
-			dataMap, ok := req.Data.(map[string]interface{})
-			if !ok {
+			var printReq PrintRequest
+			if err := decodeData(req.Data, &printReq); err != nil {
 				conn.WriteJSON(Response{Status: "error", Message: "Formato inválido em Data (esperado objeto)"})
 				continue
 			}
 
-			text, _ := dataMap["text"].(string)
-			if text == "" {
-				conn.WriteJSON(Response{Status: "error", Message: "Campo 'text' é obrigatório"})
+			// Conteúdo binário chega no próximo frame
+			if printReq.Binary {
+				pendingBinary = &printReq
+				conn.WriteJSON(Response{Status: "ok", Message: "Aguardando conteúdo binário"})
 				continue
 			}
 
-			printerName, _ := dataMap["printer"].(string)
-			printerName = resolvePrinterName(printerName)
+			printFromWS(conn, printReq, nil, printReq.contentType())
 
-			// Envia para impressão
-			log.Printf("WebSocket: Solicitando impressão na impressora [%s] (tamanho texto: %d)", printerName, len(text))
-			if err := printToOS(printerName, text); err != nil {
-				log.Printf("WebSocket: Erro ao imprimir: %v", err)
+		case "open_drawer":
+			// Qualquer página aberta no navegador alcança o WebSocket, então a
+			// gaveta exige o mesmo token do POST /drawer/open
+			var data struct {
+				DrawerRequest
+				Token string `json:"token"`
+			}
+			if req.Data != nil {
+				if err := decodeData(req.Data, &data); err != nil {
+					conn.WriteJSON(Response{Status: "error", Message: "Formato inválido em Data (esperado objeto)"})
+					continue
+				}
+			}
+			caller, ok := drawerTokenCaller(data.Token)
+			if !ok {
+				log.Printf("WebSocket: Abertura da gaveta recusada para %s: token ausente ou inválido", r.RemoteAddr)
+				recordAudit(AuditEntry{Action: "open_drawer", Claimed: data.User, Source: SourceWebSocket, Remote: r.RemoteAddr, Requested: data.Printer, Status: "error", Error: "não autorizado"})
+				conn.WriteJSON(Response{Status: "error", Message: "Não autorizado"})
+				continue
+			}
+			job, err := openDrawer(data.DrawerRequest, caller, SourceWebSocket, r.RemoteAddr)
+			if err != nil {
 				conn.WriteJSON(Response{Status: "error", Message: err.Error()})
 				continue
 			}
-
-			log.Printf("WebSocket: Impressão enviada com sucesso para [%s]", printerName)
-			conn.WriteJSON(Response{Status: "ok", Message: "Impressão enviada"})
+			conn.WriteJSON(Response{Status: "ok", Message: "Gaveta aberta", Data: map[string]string{"job_id": job.ID, "printer": job.Printer}})
 
 		case "config":
-			dataMap, ok := req.Data.(map[string]interface{})
-			if !ok {
-				conn.WriteJSON(Response{Status: "error", Message: "Formato inválido em Data (esperado objeto)"})
+			config, err := applyConfig(req.Data)
+			if err != nil {
+				conn.WriteJSON(Response{Status: "error", Message: err.Error()})
 				continue
 			}
+			if tokens.Expired() {
+				tokens.Renew(config.AccessToken)
+			}
+			log.Printf("WebSocket: Configuração aplicada com sucesso. Backend: %s, RabbitMQ: %s, Agente: %s", config.BackendURL, config.RabbitMQURL, config.GetAgentID())
 
-			config := &Config{
-				AccessToken: dataMap["access_token"].(string),
-				SchemaName:  dataMap["schema_name"].(string),
-				BackendURL:  dataMap["backend_url"].(string),
-				RabbitMQURL: dataMap["rabbitmq_url"].(string),
+			// Inicia ou reinicia o consumidor RabbitMQ
+			log.Printf("WebSocket: Disparando início do consumidor RabbitMQ...")
+			go startRabbitMQConsumer()
+
+			conn.WriteJSON(Response{Status: "ok", Message: "Configuração aplicada"})
+
+		case "set_token":
+			var data struct {
+				AccessToken string `json:"access_token"`
+			}
+			if err := decodeData(req.Data, &data); err != nil || data.AccessToken == "" {
+				conn.WriteJSON(Response{Status: "error", Message: "Campo 'access_token' é obrigatório"})
+				continue
+			}
+			if GlobalConfig == nil {
+				conn.WriteJSON(Response{Status: "error", Message: "Agente ainda não configurado"})
+				continue
 			}
 
-			if config.AccessToken == "" || config.SchemaName == "" || config.BackendURL == "" || config.RabbitMQURL == "" {
-				conn.WriteJSON(Response{Status: "error", Message: "Configuração incompleta. access_token, schema_name, backend_url e rabbitmq_url são obrigatórios."})
+			tokens.Renew(data.AccessToken)
+			log.Printf("WebSocket: Token de acesso renovado por %s", r.RemoteAddr)
+			conn.WriteJSON(Response{Status: "ok", Message: "Token atualizado"})
+
+		case "get_templates":
+			names, err := listTemplates()
+			if err != nil {
+				conn.WriteJSON(Response{Status: "error", Message: err.Error()})
 				continue
 			}
+			conn.WriteJSON(Response{Status: "ok", Data: names})
 
-			log.Printf("WebSocket: Iniciando aplicação de configuração para schema [%s]", config.SchemaName)
-			GlobalConfig = config
-			log.Printf("WebSocket: Configuração aplicada com sucesso. Backend: %s, RabbitMQ: %s", GlobalConfig.BackendURL, GlobalConfig.RabbitMQURL)
+		case "get_template", "save_template", "delete_template":
+			var data struct {
+				Name    string `json:"name"`
+				Content string `json:"content"`
+			}
+			if err := decodeData(req.Data, &data); err != nil || data.Name == "" {
+				conn.WriteJSON(Response{Status: "error", Message: "Campo 'name' é obrigatório"})
+				continue
+			}
 
-			// Inicia ou reinicia o consumidor RabbitMQ
-			log.Printf("WebSocket: Disparando início do consumidor RabbitMQ...")
-			go startRabbitMQConsumer()
+			switch req.Action {
+			case "get_template":
+				text, err := readTemplate(data.Name)
+				if err != nil {
+					conn.WriteJSON(Response{Status: "error", Message: err.Error()})
+					continue
+				}
+				conn.WriteJSON(Response{Status: "ok", Data: map[string]string{"name": data.Name, "content": text}})
+			case "save_template":
+				if err := saveTemplate(data.Name, data.Content); err != nil {
+					conn.WriteJSON(Response{Status: "error", Message: err.Error()})
+					continue
+				}
+				log.Printf("WebSocket: Template [%s] salvo por %s", data.Name, r.RemoteAddr)
+				conn.WriteJSON(Response{Status: "ok", Message: "Template salvo"})
+			case "delete_template":
+				if err := deleteTemplate(data.Name); err != nil {
+					conn.WriteJSON(Response{Status: "error", Message: err.Error()})
+					continue
+				}
+				log.Printf("WebSocket: Template [%s] removido por %s", data.Name, r.RemoteAddr)
+				conn.WriteJSON(Response{Status: "ok", Message: "Template removido"})
+			}
 
-			conn.WriteJSON(Response{Status: "ok", Message: "Configuração aplicada"})
+		case "set_printer_settings":
+			var data struct {
+				Printer  string        `json:"printer"`
+				Settings PrinterConfig `json:"settings"`
+			}
+			if err := decodeData(req.Data, &data); err != nil || data.Printer == "" {
+				conn.WriteJSON(Response{Status: "error", Message: "Campo 'printer' é obrigatório"})
+				continue
+			}
+			if GlobalConfig == nil {
+				conn.WriteJSON(Response{Status: "error", Message: "Agente ainda não configurado"})
+				continue
+			}
+			if err := data.Settings.Validate(); err != nil {
+				conn.WriteJSON(Response{Status: "error", Message: err.Error()})
+				continue
+			}
+			err := updateConfig(func(config *Config) error {
+				if data.Settings.Profile != "" && !config.hasProfile(data.Settings.Profile) {
+					return fmt.Errorf("Perfil desconhecido: %s", data.Settings.Profile)
+				}
+				if config.Printers == nil {
+					config.Printers = map[string]PrinterConfig{}
+				}
+				config.Printers[data.Printer] = data.Settings
+				return nil
+			})
+			if err != nil {
+				conn.WriteJSON(Response{Status: "error", Message: err.Error()})
+				continue
+			}
+			log.Printf("WebSocket: Configuração da impressora [%s] atualizada por %s", data.Printer, r.RemoteAddr)
+			conn.WriteJSON(Response{Status: "ok", Message: "Configuração da impressora salva"})
+
+		case "get_profiles":
+			printers, err := printerProfiles()
+			if err != nil {
+				log.Printf("WebSocket: Erro ao listar impressoras: %v", err)
+			}
+			conn.WriteJSON(Response{Status: "ok", Data: map[string]interface{}{
+				"profiles": listProfiles(),
+				"printers": printers,
+			}})
+
+		case "save_profile", "delete_profile":
+			var data struct {
+				Name    string         `json:"name"`
+				Profile escpos.Profile `json:"profile"`
+			}
+			if err := decodeData(req.Data, &data); err != nil || data.Name == "" {
+				conn.WriteJSON(Response{Status: "error", Message: "Campo 'name' é obrigatório"})
+				continue
+			}
+
+			if req.Action == "save_profile" {
+				if err := saveProfile(data.Name, data.Profile); err != nil {
+					conn.WriteJSON(Response{Status: "error", Message: err.Error()})
+					continue
+				}
+				log.Printf("WebSocket: Perfil [%s] salvo por %s", data.Name, r.RemoteAddr)
+				conn.WriteJSON(Response{Status: "ok", Message: "Perfil salvo"})
+				continue
+			}
+			if err := deleteProfile(data.Name); err != nil {
+				conn.WriteJSON(Response{Status: "error", Message: err.Error()})
+				continue
+			}
+			log.Printf("WebSocket: Perfil [%s] removido por %s", data.Name, r.RemoteAddr)
+			conn.WriteJSON(Response{Status: "ok", Message: "Perfil removido"})
+
+		case "get_stations":
+			stations, err := listStations()
+			if err != nil {
+				conn.WriteJSON(Response{Status: "error", Message: err.Error()})
+				continue
+			}
+			conn.WriteJSON(Response{Status: "ok", Data: stations})
+
+		case "set_station", "delete_station":
+			var data struct {
+				Name    string `json:"name"`
+				Printer string `json:"printer"`
+			}
+			if err := decodeData(req.Data, &data); err != nil || data.Name == "" {
+				conn.WriteJSON(Response{Status: "error", Message: "Campo 'name' é obrigatório"})
+				continue
+			}
+
+			if req.Action == "set_station" {
+				if err := saveStation(data.Name, data.Printer); err != nil {
+					conn.WriteJSON(Response{Status: "error", Message: err.Error()})
+					continue
+				}
+				log.Printf("WebSocket: Estação [%s] associada à impressora [%s] por %s", data.Name, data.Printer, r.RemoteAddr)
+				conn.WriteJSON(Response{Status: "ok", Message: "Estação salva"})
+				continue
+			}
+			if err := deleteStation(data.Name); err != nil {
+				conn.WriteJSON(Response{Status: "error", Message: err.Error()})
+				continue
+			}
+			log.Printf("WebSocket: Estação [%s] removida por %s", data.Name, r.RemoteAddr)
+			conn.WriteJSON(Response{Status: "ok", Message: "Estação removida"})
+
+		case "get_jobs":
+			var data struct {
+				Limit int `json:"limit"`
+			}
+			decodeData(req.Data, &data)
+			jobs, err := listJobs(data.Limit)
+			if err != nil {
+				conn.WriteJSON(Response{Status: "error", Message: err.Error()})
+				continue
+			}
+			conn.WriteJSON(Response{Status: "ok", Data: jobs})
+
+		case "preview":
+			var data struct {
+				JobID string `json:"job_id"`
+				PrintRequest
+			}
+			if err := decodeData(req.Data, &data); err != nil {
+				conn.WriteJSON(Response{Status: "error", Message: "Formato inválido em Data (esperado objeto)"})
+				continue
+			}
+
+			var printerName string
+			var rendered []byte
+			if data.JobID != "" {
+				job, content, err := loadJob(data.JobID)
+				if err != nil {
+					conn.WriteJSON(Response{Status: "error", Message: err.Error()})
+					continue
+				}
+				printerName, rendered = job.Printer, content
+			} else {
+				var err error
+				printerName = primaryPrinter(data.Printer)
+				rendered, err = renderWSContent(data.PrintRequest, printerName, nil, data.PrintRequest.contentType())
+				if err != nil {
+					conn.WriteJSON(Response{Status: "error", Message: err.Error()})
+					continue
+				}
+			}
+
+			image, err := renderPreview(printerName, rendered)
+			if err != nil {
+				conn.WriteJSON(Response{Status: "error", Message: err.Error()})
+				continue
+			}
+			conn.WriteJSON(Response{Status: "ok", Data: map[string]interface{}{
+				"printer":      printerName,
+				"job_id":       data.JobID,
+				"content_type": "image/png",
+				"image":        base64.StdEncoding.EncodeToString(image),
+			}})
+
+		case "health":
+			conn.WriteJSON(Response{Status: "ok", Data: getHealth()})
 
 		default:
 			conn.WriteJSON(Response{Status: "error", Message: fmt.Sprintf("Ação desconhecida: %s", req.Action)})
 		}
 	}
 }
+
+// PrintRequest é o payload da ação "print". O conteúdo pode vir como texto,
+// codificado em base64 ou em um frame binário logo em seguida (binary=true).
+type PrintRequest struct {
+	Printer string `json:"printer,omitempty"`
+
+	// Cópias e vários destinos (impressoras, estações ou grupos); com
+	// targets, printer é ignorado
+	Copies  int           `json:"copies,omitempty"`
+	Targets []PrintTarget `json:"targets,omitempty"`
+
+	Text        string `json:"text,omitempty"`
+	Data        string `json:"data,omitempty"`
+	Encoding    string `json:"encoding,omitempty"` // base64 (padrão para data)
+	ContentType string `json:"content_type,omitempty"`
+	Binary      bool   `json:"binary,omitempty"`
+
+	// Documento estruturado renderizado em ESC/POS pelo agente
+	Document json.RawMessage `json:"document,omitempty"`
+
+	// Template local renderizado com os dados informados
+	Template     string          `json:"template,omitempty"`
+	TemplateData json.RawMessage `json:"template_data,omitempty"`
+}
+
+// content decodifica o conteúdo informado em text, data ou document, ou
+// renderiza o template na largura da impressora
+func (p PrintRequest) content(width int) ([]byte, error) {
+	if p.Template != "" {
+		return applyTemplate(p.Template, p.TemplateData, width)
+	}
+	if len(p.Document) > 0 {
+		return p.Document, nil
+	}
+	if p.Text != "" {
+		return []byte(p.Text), nil
+	}
+	if p.Data == "" {
+		return nil, fmt.Errorf("Campo 'text', 'data' ou 'document' é obrigatório")
+	}
+
+	switch strings.ToLower(p.Encoding) {
+	case "", "base64":
+		decoded, err := base64.StdEncoding.DecodeString(p.Data)
+		if err != nil {
+			return nil, fmt.Errorf("Campo 'data' com base64 inválido: %v", err)
+		}
+		return decoded, nil
+	default:
+		return nil, fmt.Errorf("Codificação não suportada: %s", p.Encoding)
+	}
+}
+
+func (p PrintRequest) contentType() string {
+	switch {
+	case p.Template != "":
+		// Template pode gerar texto ou documento; detectado na renderização
+		return ""
+	case len(p.Document) > 0:
+		return receipt.ContentType
+	case p.ContentType != "":
+		return p.ContentType
+	case p.Text != "":
+		return "text/plain"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// printFromWS envia o conteúdo recebido pelo WebSocket para a impressora,
+// estação ou grupo pedido, ou para cada destino de targets. Sem content (frame binário), o conteúdo vem do
+// próprio PrintRequest.
+func printFromWS(conn *wsConn, printReq PrintRequest, content []byte, contentType string) {
+	targets, err := normalizeTargets(printReq.Printer, printReq.Copies, printReq.Targets)
+	if err != nil {
+		conn.WriteJSON(Response{Status: "error", Message: err.Error()})
+		return
+	}
+
+	log.Printf("WebSocket: Solicitando impressão em %d destino(s) (tipo: %s)", len(targets), contentType)
+	results, err := dispatchTargets(targets, Job{Source: SourceWebSocket}, func(printerName string) ([]byte, error) {
+		return renderWSContent(printReq, printerName, content, contentType)
+	}, nil)
+
+	data := map[string]interface{}{}
+	if len(printReq.Targets) > 0 {
+		data["targets"] = results
+	} else if len(results) == 1 {
+		r := results[0]
+		if r.JobID != "" {
+			data["job_id"], data["printer"] = r.JobID, r.Printer
+		}
+		if len(r.jobs) > 1 {
+			data["jobs"] = r.jobs
+		}
+	}
+	if err != nil {
+		log.Printf("WebSocket: Erro ao imprimir: %v", err)
+		conn.WriteJSON(Response{Status: "error", Message: err.Error(), Data: data})
+		return
+	}
+
+	log.Printf("WebSocket: Impressão enviada com sucesso para %d destino(s)", len(results))
+	conn.WriteJSON(Response{Status: "ok", Message: "Impressão enviada", Data: data})
+}
+
+// renderWSContent monta o conteúdo da requisição e o converte nos bytes
+// enviados à impressora
+func renderWSContent(printReq PrintRequest, printerName string, content []byte, contentType string) ([]byte, error) {
+	if content == nil {
+		var err error
+		content, err = printReq.content(printerColumns(printerName))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(content) == 0 {
+		return nil, fmt.Errorf("Conteúdo vazio")
+	}
+
+	rendered, err := renderContent(printerName, content, contentType)
+	if err != nil {
+		log.Printf("WebSocket: Erro ao renderizar conteúdo: %v", err)
+		return nil, err
+	}
+	return rendered, nil
+}
+
+// applyConfig aplica a configuração recebida sobre a atual; campos ausentes
+// mantêm o valor atual (ex.: configurações locais das impressoras). Os mapas
+// e objetos enviados substituem os atuais em vez de serem mesclados, assim
+// uma impressora ou estação fora do mapa enviado é removida. Uma falha ao
+// gravar o config.json não impede a aplicação.
+func applyConfig(data interface{}) (*Config, error) {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	config := &Config{}
+	if GlobalConfig != nil {
+		config = GlobalConfig.Clone()
+	}
+	if fields, ok := data.(map[string]interface{}); ok {
+		config.resetFields(fields)
+	}
+	if err := decodeData(data, config); err != nil {
+		return nil, fmt.Errorf("Formato inválido em Data (esperado objeto)")
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("WebSocket: Iniciando aplicação de configuração para schema [%s]", config.SchemaName)
+	if err := saveConfig(config); err != nil {
+		log.Printf("WebSocket: Erro ao salvar configuração: %v", err)
+	}
+	GlobalConfig = config
+	return config, nil
+}
+
+// decodeData converte o campo Data da requisição para a struct informada
+func decodeData(data interface{}, v interface{}) error {
+	if _, ok := data.(map[string]interface{}); !ok {
+		return fmt.Errorf("formato inválido em Data (esperado objeto)")
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
@@ -0,0 +1,38 @@
+package main
+
+// Health resume o estado do agente para a ação "health"
+type Health struct {
+	AgentID       string                `json:"agent_id,omitempty"`
+	SchemaName    string                `json:"schema_name,omitempty"`
+	Configured    bool                  `json:"configured"`
+	RabbitMQError string                `json:"rabbitmq_error,omitempty"`
+	BackendState  string                `json:"backend_circuit,omitempty"` // closed, open, half_open
+	Subscriptions []*SubscriptionStatus `json:"subscriptions"`
+}
+
+func getHealth() Health {
+	health := Health{Subscriptions: []*SubscriptionStatus{}}
+	if GlobalConfig == nil {
+		return health
+	}
+
+	health.Configured = true
+	health.AgentID = GlobalConfig.GetAgentID()
+	health.SchemaName = GlobalConfig.SchemaName
+	if apiClient != nil {
+		health.BackendState = apiClient.Breaker().State()
+	}
+
+	consumerMu.Lock()
+	defer consumerMu.Unlock()
+
+	health.RabbitMQError = consumerError
+	for _, sub := range GlobalConfig.GetSubscriptions() {
+		status, ok := consumerStatus[sub.Exchange+"|"+sub.RoutingKey]
+		if !ok {
+			status = &SubscriptionStatus{Subscription: sub, Status: "stopped"}
+		}
+		health.Subscriptions = append(health.Subscriptions, status)
+	}
+	return health
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/streadway/amqp"
+	"github.com/willjrcom/gfood-printer/internal/receipt"
+)
+
+// defaultMaxInlineBytes é o limite padrão do conteúdo enviado dentro da mensagem
+const defaultMaxInlineBytes = 512 * 1024
+
+// Cabeçalhos AMQP que descrevem o conteúdo inline quando a mensagem não informa
+const (
+	headerContentType     = "content-type"
+	headerContentEncoding = "content-transfer-encoding"
+)
+
+// errInlineTooLarge indica conteúdo acima do limite; a mensagem é descartada sem retry
+type errInlineTooLarge struct {
+	size, limit int
+}
+
+func (e errInlineTooLarge) Error() string {
+	return fmt.Sprintf("conteúdo inline com %d bytes excede o limite de %d bytes", e.size, e.limit)
+}
+
+// hasInlineContent indica se a mensagem traz o conteúdo, dispensando a busca no backend
+func (m PrintMessage) hasInlineContent() bool {
+	return m.Content != "" || len(m.Document) > 0 || len(m.TemplateData) > 0
+}
+
+// inlineContent decodifica o conteúdo enviado na própria mensagem, respeitando
+// a codificação informada no corpo, nos cabeçalhos ou nas propriedades AMQP
+func inlineContent(d amqp.Delivery, msg PrintMessage) ([]byte, string, error) {
+	encoding := msg.ContentEncoding
+	if encoding == "" {
+		encoding = headerString(d, headerContentEncoding)
+	}
+	if encoding == "" {
+		encoding = d.ContentEncoding
+	}
+	contentType := msg.ContentType
+	if contentType == "" {
+		contentType = headerString(d, headerContentType)
+	}
+	// application/json na propriedade descreve o próprio corpo da mensagem,
+	// não o conteúdo a imprimir
+	if contentType == "" && !strings.EqualFold(d.ContentType, "application/json") {
+		contentType = d.ContentType
+	}
+
+	limit := GlobalConfig.GetMaxInlineBytes()
+
+	// Documento estruturado enviado como objeto JSON
+	if len(msg.Document) > 0 {
+		if len(msg.Document) > limit {
+			return nil, "", errInlineTooLarge{size: len(msg.Document), limit: limit}
+		}
+		return []byte(msg.Document), receipt.ContentType, nil
+	}
+
+	// Dados JSON para o template
+	if len(msg.TemplateData) > 0 {
+		if len(msg.TemplateData) > limit {
+			return nil, "", errInlineTooLarge{size: len(msg.TemplateData), limit: limit}
+		}
+		return []byte(msg.TemplateData), "application/json", nil
+	}
+
+	var content []byte
+	switch strings.ToLower(encoding) {
+	case "", "identity", "8bit", "binary":
+		content = []byte(msg.Content)
+	case "base64":
+		// Evita decodificar payloads muito acima do limite
+		if base64.StdEncoding.DecodedLen(len(msg.Content)) > limit+3 {
+			return nil, "", errInlineTooLarge{size: base64.StdEncoding.DecodedLen(len(msg.Content)), limit: limit}
+		}
+		decoded, err := base64.StdEncoding.DecodeString(msg.Content)
+		if err != nil {
+			return nil, "", fmt.Errorf("conteúdo base64 inválido: %v", err)
+		}
+		content = decoded
+	default:
+		return nil, "", fmt.Errorf("codificação de conteúdo não suportada: %s", encoding)
+	}
+
+	if len(content) > limit {
+		return nil, "", errInlineTooLarge{size: len(content), limit: limit}
+	}
+	return content, contentType, nil
+}
+
+func headerString(d amqp.Delivery, key string) string {
+	for k, v := range d.Headers {
+		if strings.EqualFold(k, key) {
+			if s, ok := v.(string); ok {
+				return s
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,54 @@
+package barcode
+
+import "github.com/willjrcom/gfood-printer/internal/raster"
+
+// Quiet zones around the symbols, in modules
+const (
+	QRQuietZone     = 4
+	LinearQuietZone = 10
+)
+
+// Bitmap draws the symbol with moduleSize dots per module, including the
+// quiet zone
+func (q *QRCode) Bitmap(moduleSize int) *raster.Bitmap {
+	if moduleSize < 1 {
+		moduleSize = 1
+	}
+	side := (q.Size + 2*QRQuietZone) * moduleSize
+	bm := raster.NewBitmap(side, side)
+	for y, row := range q.Modules {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			fillRect(bm, (x+QRQuietZone)*moduleSize, (y+QRQuietZone)*moduleSize, moduleSize, moduleSize)
+		}
+	}
+	return bm
+}
+
+// Bitmap draws the bars with moduleWidth dots per module and height dots
+// tall, including the quiet zone
+func (l *Linear) Bitmap(moduleWidth, height int) *raster.Bitmap {
+	if moduleWidth < 1 {
+		moduleWidth = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+	bm := raster.NewBitmap((len(l.Modules)+2*LinearQuietZone)*moduleWidth, height)
+	for x, bar := range l.Modules {
+		if bar {
+			fillRect(bm, (x+LinearQuietZone)*moduleWidth, 0, moduleWidth, height)
+		}
+	}
+	return bm
+}
+
+func fillRect(bm *raster.Bitmap, x, y, w, h int) {
+	for dy := 0; dy < h; dy++ {
+		for dx := 0; dx < w; dx++ {
+			bm.Set(x+dx, y+dy, true)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package barcode
+
+import (
+	"fmt"
+)
+
+// Linear is an encoded 1D barcode; Modules holds one entry per narrow module,
+// true for bars. Text is the human readable interpretation.
+type Linear struct {
+	Modules []bool
+	Text    string
+}
+
+// Code 128 bar and space widths, indexed by symbol value (106 is the stop pattern)
+var code128Patterns = [107]string{
+	"212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+	"221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+	"221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+	"212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+	"231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+	"231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+	"314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+	"112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+	"111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+	"214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+	"114131", "311141", "411131", "211412", "211214", "211232", "2331112",
+}
+
+const code128StartB = 104
+
+// Code128 encodes printable ASCII text with code set B
+func Code128(data string) (*Linear, error) {
+	if data == "" {
+		return nil, fmt.Errorf("empty code128 data")
+	}
+
+	values := []int{code128StartB}
+	checksum := code128StartB
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if c < 32 || c > 126 {
+			return nil, fmt.Errorf("code128 supports printable ASCII only, got %q", c)
+		}
+		value := int(c) - 32
+		values = append(values, value)
+		checksum += (i + 1) * value
+	}
+	values = append(values, checksum%103, 106)
+
+	var l Linear
+	for _, v := range values {
+		l.appendWidths(code128Patterns[v])
+	}
+	l.Text = data
+	return &l, nil
+}
+
+// EAN-13 left hand odd parity (L) patterns; R is the complement of L and G
+// is R reversed
+var ean13LPatterns = [10]string{
+	"0001101", "0011001", "0010011", "0111101", "0100011",
+	"0110001", "0101111", "0111011", "0110111", "0001011",
+}
+
+// Parity of the left hand digits, selected by the first digit
+var ean13Parity = [10]string{
+	"LLLLLL", "LLGLGG", "LLGGLG", "LLGGGL", "LGLLGG",
+	"LGGLLG", "LGGGLL", "LGLGLG", "LGLGGL", "LGGLGL",
+}
+
+// EAN13 encodes 12 digits (the check digit is computed) or 13 digits (the
+// check digit is verified)
+func EAN13(data string) (*Linear, error) {
+	if !isDigits(data) || (len(data) != 12 && len(data) != 13) {
+		return nil, fmt.Errorf("ean13 requires 12 or 13 digits")
+	}
+
+	sum := 0
+	for i := 0; i < 12; i++ {
+		d := int(data[i] - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	check := byte('0' + (10-sum%10)%10)
+	if len(data) == 13 && data[12] != check {
+		return nil, fmt.Errorf("invalid ean13 check digit: expected %c", check)
+	}
+	digits := data[:12] + string(check)
+
+	var l Linear
+	l.appendBits("101")
+	parity := ean13Parity[digits[0]-'0']
+	for i := 1; i <= 6; i++ {
+		pattern := ean13LPatterns[digits[i]-'0']
+		if parity[i-1] == 'G' {
+			pattern = reverse(complement(pattern))
+		}
+		l.appendBits(pattern)
+	}
+	l.appendBits("01010")
+	for i := 7; i <= 12; i++ {
+		l.appendBits(complement(ean13LPatterns[digits[i]-'0']))
+	}
+	l.appendBits("101")
+	l.Text = digits
+	return &l, nil
+}
+
+// Interleaved 2 of 5 widths of each digit (n = narrow, w = wide)
+var itfPatterns = [10]string{
+	"nnwwn", "wnnnw", "nwnnw", "wwnnn", "nnwnw",
+	"wnwnn", "nwwnn", "nnnww", "wnnwn", "nwnwn",
+}
+
+// itfWide is the width of wide elements in narrow modules
+const itfWide = 3
+
+// ITF encodes an even number of digits as Interleaved 2 of 5
+func ITF(data string) (*Linear, error) {
+	if !isDigits(data) || len(data)%2 != 0 {
+		return nil, fmt.Errorf("itf requires an even number of digits")
+	}
+
+	var l Linear
+	l.appendWidths("1111")
+	for i := 0; i < len(data); i += 2 {
+		bars, spaces := itfPatterns[data[i]-'0'], itfPatterns[data[i+1]-'0']
+		widths := make([]byte, 0, 10)
+		for j := 0; j < 5; j++ {
+			widths = append(widths, itfWidth(bars[j]), itfWidth(spaces[j]))
+		}
+		l.appendWidths(string(widths))
+	}
+	l.appendWidths(string([]byte{itfWidth('w'), '1', '1'}))
+	l.Text = data
+	return &l, nil
+}
+
+func itfWidth(c byte) byte {
+	if c == 'w' {
+		return '0' + itfWide
+	}
+	return '1'
+}
+
+// appendWidths appends alternating bars and spaces, starting with a bar
+func (l *Linear) appendWidths(widths string) {
+	for i := 0; i < len(widths); i++ {
+		for n := 0; n < int(widths[i]-'0'); n++ {
+			l.Modules = append(l.Modules, i%2 == 0)
+		}
+	}
+}
+
+// appendBits appends modules written as 1 (bar) and 0 (space)
+func (l *Linear) appendBits(bits string) {
+	for i := 0; i < len(bits); i++ {
+		l.Modules = append(l.Modules, bits[i] == '1')
+	}
+}
+
+func complement(bits string) string {
+	out := []byte(bits)
+	for i, b := range out {
+		if b == '0' {
+			out[i] = '1'
+		} else {
+			out[i] = '0'
+		}
+	}
+	return string(out)
+}
+
+func reverse(s string) string {
+	out := []byte(s)
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
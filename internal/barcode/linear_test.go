@@ -0,0 +1,170 @@
+package barcode
+
+import (
+	"strings"
+	"testing"
+)
+
+func moduleString(l *Linear) string {
+	var sb strings.Builder
+	for _, m := range l.Modules {
+		if m {
+			sb.WriteByte('1')
+		} else {
+			sb.WriteByte('0')
+		}
+	}
+	return sb.String()
+}
+
+// widthString turns the modules back into alternating bar and space widths
+func widthString(modules []bool) string {
+	var sb strings.Builder
+	run := 1
+	for i := 1; i <= len(modules); i++ {
+		if i < len(modules) && modules[i] == modules[i-1] {
+			run++
+			continue
+		}
+		sb.WriteByte(byte('0' + run))
+		run = 1
+	}
+	return sb.String()
+}
+
+func TestEAN13(t *testing.T) {
+	want := "101" +
+		"0001101" + "0100111" + "0101111" + "0111101" + "0001001" + "0110011" +
+		"01010" +
+		"1000010" + "1000010" + "1000010" + "1110100" + "1000010" + "1100110" +
+		"101"
+
+	for _, data := range []string{"4006381333931", "400638133393"} {
+		l, err := EAN13(data)
+		if err != nil {
+			t.Fatalf("%s: %v", data, err)
+		}
+		if got := moduleString(l); got != want {
+			t.Errorf("%s:\n got %s\nwant %s", data, got, want)
+		}
+		if l.Text != "4006381333931" {
+			t.Errorf("%s: text = %q", data, l.Text)
+		}
+	}
+}
+
+func TestEAN13CheckDigit(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{"789100031550", "7891000315507"},
+		{"590123412345", "5901234123457"},
+		{"000000000000", "0000000000000"},
+	}
+	for _, tt := range tests {
+		l, err := EAN13(tt.data)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.data, err)
+		}
+		if l.Text != tt.want {
+			t.Errorf("%s: text = %q, want %q", tt.data, l.Text, tt.want)
+		}
+	}
+}
+
+func TestEAN13Invalid(t *testing.T) {
+	for _, data := range []string{"4006381333932", "40063813339", "40063813339310", "40063813339a", ""} {
+		if _, err := EAN13(data); err == nil {
+			t.Errorf("%q accepted", data)
+		}
+	}
+}
+
+func TestCode128Patterns(t *testing.T) {
+	anchors := map[int]string{
+		0: "212222", 1: "222122", 2: "222221", 16: "123122", 17: "123221", 33: "111323",
+		103: "211412", 104: "211214", 105: "211232", 106: "2331112",
+	}
+	for v, want := range anchors {
+		if code128Patterns[v] != want {
+			t.Errorf("value %d: pattern %s, want %s", v, code128Patterns[v], want)
+		}
+	}
+
+	seen := map[string]int{}
+	for v, p := range code128Patterns {
+		if prev, ok := seen[p]; ok {
+			t.Errorf("values %d and %d share pattern %s", prev, v, p)
+		}
+		seen[p] = v
+		if v == 106 {
+			continue
+		}
+		total, bars := 0, 0
+		for i := 0; i < len(p); i++ {
+			w := int(p[i] - '0')
+			total += w
+			if i%2 == 0 {
+				bars += w
+			}
+		}
+		if len(p) != 6 || total != 11 || bars%2 != 0 {
+			t.Errorf("value %d: pattern %s is not 6 elements of 11 modules with even bars", v, p)
+		}
+	}
+}
+
+func TestCode128(t *testing.T) {
+	// Start B 104, then P=48 J=42 J=42 1=17 2=18 3=19 C=35;
+	// 104 + 48*1 + 42*2 + 42*3 + 17*4 + 18*5 + 19*6 + 35*7 = 879, 879 % 103 = 55
+	values := []int{104, 48, 42, 42, 17, 18, 19, 35, 55, 106}
+	var want strings.Builder
+	for _, v := range values {
+		want.WriteString(code128Patterns[v])
+	}
+
+	l, err := Code128("PJJ123C")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := widthString(l.Modules); got != want.String() {
+		t.Errorf("widths:\n got %s\nwant %s", got, want.String())
+	}
+	if len(l.Modules) != 11*len(values)+2 {
+		t.Errorf("%d modules, want %d", len(l.Modules), 11*len(values)+2)
+	}
+	if !l.Modules[0] || !l.Modules[len(l.Modules)-1] {
+		t.Error("symbol must start and end with a bar")
+	}
+}
+
+func TestCode128Invalid(t *testing.T) {
+	for _, data := range []string{"", "caf\u00e9", "a\tb"} {
+		if _, err := Code128(data); err == nil {
+			t.Errorf("%q accepted", data)
+		}
+	}
+}
+
+func TestITF(t *testing.T) {
+	// Start 1010; digit 1 (wnnnw) in the bars and 2 (nwnnw) in the spaces;
+	// stop wide bar, narrow space, narrow bar
+	want := "1010" + "111" + "0" + "1" + "000" + "1" + "0" + "1" + "0" + "111" + "000" + "11101"
+
+	l, err := ITF("12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := moduleString(l); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestITFInvalid(t *testing.T) {
+	for _, data := range []string{"", "123", "12a4"} {
+		if _, err := ITF(data); err == nil {
+			t.Errorf("%q accepted", data)
+		}
+	}
+}
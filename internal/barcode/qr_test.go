@@ -0,0 +1,461 @@
+package barcode
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestReedSolomonRemainder(t *testing.T) {
+	// ISO/IEC 18004 Annex I: "01234567" as version 1-M
+	data := []byte{0x10, 0x20, 0x0C, 0x56, 0x61, 0x80, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11}
+	want := []byte{0xA5, 0x24, 0xD4, 0xC1, 0xED, 0x36, 0xC7, 0x87, 0x2C, 0x55}
+
+	got := reedSolomonRemainder(data, reedSolomonDivisor(len(want)))
+	if !bytes.Equal(got, want) {
+		t.Fatalf("ecc = % X, want % X", got, want)
+	}
+}
+
+// qrFormatTable lists the 15 format bits of ISO/IEC 18004 Table C.1 by level and mask
+var qrFormatTable = [4][8]string{
+	QRLevelL: {"111011111000100", "111001011110011", "111110110101010", "111100010011101", "110011000101111", "110001100011000", "110110001000001", "110100101110110"},
+	QRLevelM: {"101010000010010", "101000100100101", "101111001111100", "101101101001011", "100010111111001", "100000011001110", "100111110010111", "100101010100000"},
+	QRLevelQ: {"011010101011111", "011000001101000", "011111100110001", "011101000000110", "010010010110100", "010000110000011", "010111011011010", "010101111101101"},
+	QRLevelH: {"001011010001001", "001001110111110", "001110011100111", "001100111010000", "000011101100010", "000001001010101", "000110100001100", "000100000111011"},
+}
+
+func TestQRFormatInfo(t *testing.T) {
+	for level, masks := range qrFormatTable {
+		for mask, want := range masks {
+			if got := fmt.Sprintf("%015b", qrFormatInfo(level, mask)); got != want {
+				t.Errorf("level %d mask %d: format = %s, want %s", level, mask, got, want)
+			}
+		}
+	}
+}
+
+func TestQRVersionInfo(t *testing.T) {
+	// ISO/IEC 18004 Table D.1
+	tests := []struct {
+		version int
+		want    int
+	}{
+		{7, 0x07C94},
+		{8, 0x085BC},
+		{9, 0x09A99},
+		{10, 0x0A4D3},
+		{21, 0x15683},
+		{40, 0x28C69},
+	}
+	for _, tt := range tests {
+		if got := qrVersionInfo(tt.version); got != tt.want {
+			t.Errorf("version %d: info = %05X, want %05X", tt.version, got, tt.want)
+		}
+	}
+}
+
+// qrSpecBlocks is the error correction structure of ISO/IEC 18004 Table 9
+// for versions 1 to 10: codewords per block, then pairs of block count and
+// data codewords per block
+var qrSpecBlocks = map[int][4][]int{
+	1:  {{7, 1, 19}, {10, 1, 16}, {13, 1, 13}, {17, 1, 9}},
+	2:  {{10, 1, 34}, {16, 1, 28}, {22, 1, 22}, {28, 1, 16}},
+	3:  {{15, 1, 55}, {26, 1, 44}, {18, 2, 17}, {22, 2, 13}},
+	4:  {{20, 1, 80}, {18, 2, 32}, {26, 2, 24}, {16, 4, 9}},
+	5:  {{26, 1, 108}, {24, 2, 43}, {18, 2, 15, 2, 16}, {22, 2, 11, 2, 12}},
+	6:  {{18, 2, 68}, {16, 4, 27}, {24, 4, 19}, {28, 4, 15}},
+	7:  {{20, 2, 78}, {18, 4, 31}, {18, 2, 14, 4, 15}, {26, 4, 13, 1, 14}},
+	8:  {{24, 2, 97}, {22, 2, 38, 2, 39}, {22, 4, 18, 2, 19}, {26, 4, 14, 2, 15}},
+	9:  {{30, 2, 116}, {22, 3, 36, 2, 37}, {20, 4, 16, 4, 17}, {24, 4, 12, 4, 13}},
+	10: {{18, 2, 68, 2, 69}, {26, 4, 43, 1, 44}, {24, 6, 19, 2, 20}, {28, 6, 15, 2, 16}},
+}
+
+// qrSpecAlignment lists the alignment pattern centres of ISO/IEC 18004 Annex E
+var qrSpecAlignment = map[int][]int{
+	2: {6, 18}, 3: {6, 22}, 4: {6, 26}, 5: {6, 30}, 6: {6, 34},
+	7: {6, 22, 38}, 8: {6, 24, 42}, 9: {6, 26, 46}, 10: {6, 28, 50},
+}
+
+// qrSpecDataBlocks returns the data codewords of each block
+func qrSpecDataBlocks(version, level int) (ecc int, blocks []int) {
+	spec := qrSpecBlocks[version][level]
+	for i := 1; i < len(spec); i += 2 {
+		for n := 0; n < spec[i]; n++ {
+			blocks = append(blocks, spec[i+1])
+		}
+	}
+	return spec[0], blocks
+}
+
+// qrSpecByteCapacity returns how many bytes fit in byte mode
+func qrSpecByteCapacity(version, level int) int {
+	_, blocks := qrSpecDataBlocks(version, level)
+	total := 0
+	for _, n := range blocks {
+		total += n
+	}
+	countBits := 8
+	if version >= 10 {
+		countBits = 16
+	}
+	return (total*8 - 4 - countBits) / 8
+}
+
+func TestEncodeQRCapacity(t *testing.T) {
+	// ISO/IEC 18004 Table 7, byte mode
+	tests := []struct {
+		version int
+		level   int
+		want    int
+	}{
+		{1, QRLevelL, 17}, {1, QRLevelM, 14}, {1, QRLevelQ, 11}, {1, QRLevelH, 7},
+		{2, QRLevelL, 32}, {2, QRLevelM, 26}, {2, QRLevelQ, 20}, {2, QRLevelH, 14},
+		{5, QRLevelL, 106}, {5, QRLevelM, 84}, {5, QRLevelQ, 60}, {5, QRLevelH, 44},
+		{10, QRLevelL, 271}, {10, QRLevelM, 213}, {10, QRLevelQ, 151}, {10, QRLevelH, 119},
+		{40, QRLevelL, 2953}, {40, QRLevelM, 2331}, {40, QRLevelQ, 1663}, {40, QRLevelH, 1273},
+	}
+	for _, tt := range tests {
+		qr, err := EncodeQR(make([]byte, tt.want), tt.level)
+		if err != nil {
+			t.Errorf("%d-%d: %d bytes: %v", tt.version, tt.level, tt.want, err)
+			continue
+		}
+		if qr.Version != tt.version || qr.Size != tt.version*4+17 {
+			t.Errorf("%d-%d: %d bytes encoded as version %d size %d", tt.version, tt.level, tt.want, qr.Version, qr.Size)
+		}
+
+		qr, err = EncodeQR(make([]byte, tt.want+1), tt.level)
+		if tt.version == 40 {
+			if err == nil {
+				t.Errorf("40-%d: %d bytes accepted", tt.level, tt.want+1)
+			}
+			continue
+		}
+		if err != nil || qr.Version != tt.version+1 {
+			t.Errorf("%d-%d: %d bytes did not move to the next version: %v", tt.version, tt.level, tt.want+1, err)
+		}
+	}
+}
+
+func TestEncodeQRInvalidLevel(t *testing.T) {
+	if _, err := EncodeQR([]byte("x"), 4); err == nil {
+		t.Fatal("level 4 accepted")
+	}
+}
+
+// TestEncodeQRDecode reads every symbol back with a decoder written from the
+// standard, so layout, masking, interleaving and error correction are all
+// checked independently of the encoder
+func TestEncodeQRDecode(t *testing.T) {
+	for version := 1; version <= 10; version++ {
+		for level := QRLevelL; level <= QRLevelH; level++ {
+			n := qrSpecByteCapacity(version, level)
+			for _, size := range []int{n, n - 1} {
+				if version > 1 && size <= qrSpecByteCapacity(version-1, level) {
+					continue
+				}
+				data := make([]byte, size)
+				for i := range data {
+					data[i] = byte(i*37 + version*11 + level)
+				}
+				name := fmt.Sprintf("%d-%d/%d", version, level, size)
+
+				qr, err := EncodeQR(data, level)
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				if qr.Version != version {
+					t.Fatalf("%s: encoded as version %d", name, qr.Version)
+				}
+				got, err := decodeQR(qr, level)
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				if !bytes.Equal(got, data) {
+					t.Fatalf("%s: decoded % X, want % X", name, got, data)
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeQRShortText(t *testing.T) {
+	for level := QRLevelL; level <= QRLevelH; level++ {
+		data := []byte("https://example.com/pedido/123")
+		qr, err := EncodeQR(data, level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := decodeQR(qr, level)
+		if err != nil {
+			t.Fatalf("level %d: %v", level, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("level %d: decoded %q", level, got)
+		}
+	}
+}
+
+// decodeQR decodes a byte mode symbol of version 1 to 10
+func decodeQR(qr *QRCode, level int) ([]byte, error) {
+	size := qr.Size
+	version := (size - 17) / 4
+	dark := func(x, y int) bool { return qr.Modules[y][x] }
+
+	if len(qr.Modules) != size {
+		return nil, fmt.Errorf("%d rows for size %d", len(qr.Modules), size)
+	}
+	for _, c := range [][2]int{{0, 0}, {size - 7, 0}, {0, size - 7}} {
+		for dy := 0; dy < 7; dy++ {
+			for dx := 0; dx < 7; dx++ {
+				ring := dx == 0 || dx == 6 || dy == 0 || dy == 6
+				core := dx >= 2 && dx <= 4 && dy >= 2 && dy <= 4
+				if dark(c[0]+dx, c[1]+dy) != (ring || core) {
+					return nil, fmt.Errorf("finder at %v broken at %d,%d", c, dx, dy)
+				}
+			}
+		}
+	}
+	for i := 8; i < size-8; i++ {
+		if dark(i, 6) != (i%2 == 0) || dark(6, i) != (i%2 == 0) {
+			return nil, fmt.Errorf("timing pattern broken at %d", i)
+		}
+	}
+	if !dark(8, size-8) {
+		return nil, fmt.Errorf("dark module missing")
+	}
+
+	// Format information, most significant bit first
+	var format1, format2 int
+	read := func(v *int, x, y int) {
+		*v <<= 1
+		if dark(x, y) {
+			*v |= 1
+		}
+	}
+	for x := 0; x < 6; x++ {
+		read(&format1, x, 8)
+	}
+	read(&format1, 7, 8)
+	read(&format1, 8, 8)
+	read(&format1, 8, 7)
+	for y := 5; y >= 0; y-- {
+		read(&format1, 8, y)
+	}
+	for y := size - 1; y >= size-7; y-- {
+		read(&format2, 8, y)
+	}
+	for x := size - 8; x < size; x++ {
+		read(&format2, x, 8)
+	}
+	if format1 != format2 {
+		return nil, fmt.Errorf("format copies differ: %015b %015b", format1, format2)
+	}
+	mask := -1
+	for m, bits := range qrFormatTable[level] {
+		if fmt.Sprintf("%015b", format1) == bits {
+			mask = m
+		}
+	}
+	if mask < 0 {
+		return nil, fmt.Errorf("format %015b is not a level %d code", format1, level)
+	}
+
+	// Version information, most significant bit first
+	if version >= 7 {
+		var info1, info2 int
+		for j := 5; j >= 0; j-- {
+			for i := size - 9; i >= size-11; i-- {
+				read(&info1, i, j)
+				read(&info2, j, i)
+			}
+		}
+		want := map[int]int{7: 0x07C94, 8: 0x085BC, 9: 0x09A99, 10: 0x0A4D3}[version]
+		if info1 != want || info2 != want {
+			return nil, fmt.Errorf("version info %05X %05X, want %05X", info1, info2, want)
+		}
+	}
+
+	// Function modules
+	function := make([][]bool, size)
+	for y := range function {
+		function[y] = make([]bool, size)
+	}
+	region := func(x0, y0, w, h int) {
+		for y := y0; y < y0+h; y++ {
+			for x := x0; x < x0+w; x++ {
+				function[y][x] = true
+			}
+		}
+	}
+	region(0, 0, 9, 9)
+	region(size-8, 0, 8, 9)
+	region(0, size-8, 9, 8)
+	region(6, 0, 1, size)
+	region(0, 6, size, 1)
+	align := qrSpecAlignment[version]
+	for i, x := range align {
+		for j, y := range align {
+			last := len(align) - 1
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			region(x-2, y-2, 5, 5)
+		}
+	}
+	if version >= 7 {
+		region(size-11, 0, 3, 6)
+		region(0, size-11, 6, 3)
+	}
+
+	masked := func(x, y int) bool {
+		i, j := y, x
+		switch mask {
+		case 0:
+			return (i+j)%2 == 0
+		case 1:
+			return i%2 == 0
+		case 2:
+			return j%3 == 0
+		case 3:
+			return (i+j)%3 == 0
+		case 4:
+			return (i/2+j/3)%2 == 0
+		case 5:
+			return (i*j)%2+(i*j)%3 == 0
+		case 6:
+			return ((i*j)%2+(i*j)%3)%2 == 0
+		default:
+			return ((i+j)%2+(i*j)%3)%2 == 0
+		}
+	}
+
+	// Codewords in placement order
+	var raw []byte
+	var cur byte
+	bits := 0
+	up := true
+	for right := size - 1; right > 0; right -= 2 {
+		if right == 6 {
+			right--
+		}
+		for count := 0; count < size; count++ {
+			y := count
+			if up {
+				y = size - 1 - count
+			}
+			for col := 0; col < 2; col++ {
+				x := right - col
+				if function[y][x] {
+					continue
+				}
+				cur <<= 1
+				if dark(x, y) != masked(x, y) {
+					cur |= 1
+				}
+				if bits++; bits == 8 {
+					raw = append(raw, cur)
+					cur, bits = 0, 0
+				}
+			}
+		}
+		up = !up
+	}
+
+	// De-interleave and check each block
+	ecc, lengths := qrSpecDataBlocks(version, level)
+	blocks := make([][]byte, len(lengths))
+	k := 0
+	for i := 0; i < lengths[len(lengths)-1]; i++ {
+		for b, n := range lengths {
+			if i < n {
+				blocks[b] = append(blocks[b], raw[k])
+				k++
+			}
+		}
+	}
+	for i := 0; i < ecc; i++ {
+		for b := range blocks {
+			blocks[b] = append(blocks[b], raw[k])
+			k++
+		}
+	}
+	var stream []byte
+	for b, block := range blocks {
+		if !qrSyndromesZero(block, ecc) {
+			return nil, fmt.Errorf("block %d fails the error correction check", b)
+		}
+		stream = append(stream, block[:lengths[b]]...)
+	}
+
+	// Byte mode segment, terminator and padding
+	pos := 0
+	take := func(n int) int {
+		v := 0
+		for i := 0; i < n; i++ {
+			v = v<<1 | int(stream[pos/8]>>uint(7-pos%8))&1
+			pos++
+		}
+		return v
+	}
+	if m := take(4); m != 0x4 {
+		return nil, fmt.Errorf("mode %04b, want byte mode", m)
+	}
+	countBits := 8
+	if version >= 10 {
+		countBits = 16
+	}
+	out := make([]byte, take(countBits))
+	for i := range out {
+		out[i] = byte(take(8))
+	}
+	for i := 0; i < 4 && pos < len(stream)*8; i++ {
+		if take(1) != 0 {
+			return nil, fmt.Errorf("terminator is not zero")
+		}
+	}
+	for pos%8 != 0 {
+		if take(1) != 0 {
+			return nil, fmt.Errorf("bit padding is not zero")
+		}
+	}
+	for pad := 0xEC; pos < len(stream)*8; pad ^= 0xEC ^ 0x11 {
+		if got := take(8); got != pad {
+			return nil, fmt.Errorf("pad byte %02X, want %02X", got, pad)
+		}
+	}
+	return out, nil
+}
+
+// qrSyndromesZero evaluates the block at the generator roots a^0..a^(ecc-1)
+// using log tables built for x^8 + x^4 + x^3 + x^2 + 1
+func qrSyndromesZero(block []byte, ecc int) bool {
+	var exp [255]int
+	var log [256]int
+	v := 1
+	for i := 0; i < 255; i++ {
+		exp[i] = v
+		log[v] = i
+		v <<= 1
+		if v&0x100 != 0 {
+			v ^= 0x11D
+		}
+	}
+	mul := func(a, b int) int {
+		if a == 0 || b == 0 {
+			return 0
+		}
+		return exp[(log[a]+log[b])%255]
+	}
+	for r := 0; r < ecc; r++ {
+		s := 0
+		for _, c := range block {
+			s = mul(s, exp[r]) ^ int(c)
+		}
+		if s != 0 {
+			return false
+		}
+	}
+	return true
+}
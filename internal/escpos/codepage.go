@@ -0,0 +1,174 @@
+package escpos
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// CodePage maps Unicode text to a single-byte printer code page
+type CodePage struct {
+	Name   string
+	Number byte // n of ESC t n on Epson compatible printers
+
+	table   *[128]rune
+	once    sync.Once
+	reverse map[rune]byte
+}
+
+var codePages = map[string]*CodePage{
+	"cp437":   {Name: "cp437", Number: 0, table: &tableCP437},
+	"cp850":   {Name: "cp850", Number: 2, table: &tableCP850},
+	"cp860":   {Name: "cp860", Number: 3, table: &tableCP860},
+	"wpc1252": {Name: "wpc1252", Number: 16, table: &tableWPC1252},
+	"cp858":   {Name: "cp858", Number: 19, table: &tableCP858},
+}
+
+var codePageAliases = map[string]string{
+	"pc437":        "cp437",
+	"pc850":        "cp850",
+	"pc860":        "cp860",
+	"pc858":        "cp858",
+	"cp1252":       "wpc1252",
+	"windows-1252": "wpc1252",
+}
+
+// LookupCodePage finds a code page by name (cp437, cp850, cp858, cp860, wpc1252)
+func LookupCodePage(name string) (*CodePage, error) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := codePageAliases[key]; ok {
+		key = alias
+	}
+	cp, ok := codePages[key]
+	if !ok {
+		return nil, fmt.Errorf("unknown code page %q (available: %s)", name, strings.Join(CodePageNames(), ", "))
+	}
+	return cp, nil
+}
+
+// CodePageNames lists the supported code pages
+func CodePageNames() []string {
+	names := make([]string, 0, len(codePages))
+	for name := range codePages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupCodePageNumber finds a code page by its Epson ESC t number
+func LookupCodePageNumber(n byte) (*CodePage, bool) {
+	for _, cp := range codePages {
+		if cp.Number == n {
+			return cp, true
+		}
+	}
+	return nil, false
+}
+
+// Decode returns the character printed for the byte
+func (cp *CodePage) Decode(b byte) rune {
+	if b < 0x80 {
+		return rune(b)
+	}
+	if r := cp.table[b-0x80]; r != 0 {
+		return r
+	}
+	return '?'
+}
+
+// Transliterate returns the ASCII replacement used for characters missing
+// from a code page
+func Transliterate(r rune) string {
+	if r < 0x80 {
+		return string(r)
+	}
+	if ascii, ok := transliterations[r]; ok {
+		return ascii
+	}
+	return "?"
+}
+
+// WithNumber returns a copy of the code page selected by another ESC t n value,
+// for printers that number their tables differently
+func (cp *CodePage) WithNumber(n byte) *CodePage {
+	return &CodePage{Name: cp.Name, Number: n, table: cp.table}
+}
+
+func (cp *CodePage) buildReverse() {
+	cp.reverse = make(map[rune]byte, 128)
+	for i, r := range cp.table {
+		if r != 0 {
+			cp.reverse[r] = byte(0x80 + i)
+		}
+	}
+}
+
+// appendRune encodes the character, transliterating it to ASCII when the
+// code page does not have it
+func (cp *CodePage) appendRune(dst []byte, r rune) []byte {
+	if r < 0x80 {
+		return append(dst, byte(r))
+	}
+	cp.once.Do(cp.buildReverse)
+	if b, ok := cp.reverse[r]; ok {
+		return append(dst, b)
+	}
+	if ascii, ok := transliterations[r]; ok {
+		return append(dst, ascii...)
+	}
+	return append(dst, '?')
+}
+
+// Encode converts UTF-8 text to the code page
+func (cp *CodePage) Encode(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		out = cp.appendRune(out, r)
+	}
+	return out
+}
+
+// Select returns the ESC t n command that selects the code page
+func (cp *CodePage) Select() []byte {
+	return []byte{ESC, 't', cp.Number}
+}
+
+// EncodeText converts UTF-8 text mixed with ESC/POS commands to the code page.
+// The selection command is written first and repeated after every ESC @,
+// since initializing the printer resets the code page. Bytes that are not
+// valid UTF-8 are copied unchanged.
+func (cp *CodePage) EncodeText(data []byte) []byte {
+	out := make([]byte, 0, len(data)+8)
+	out = append(out, cp.Select()...)
+	for i := 0; i < len(data); {
+		b := data[i]
+		if b == ESC && i+1 < len(data) && data[i+1] == '@' {
+			out = append(out, ESC, '@')
+			out = append(out, cp.Select()...)
+			i += 2
+			continue
+		}
+		if b < utf8.RuneSelf {
+			out = append(out, b)
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(data[i:])
+		if r == utf8.RuneError && size <= 1 {
+			out = append(out, b)
+			i++
+			continue
+		}
+		out = cp.appendRune(out, r)
+		i += size
+	}
+	return out
+}
+
+// CodePage writes the ESC t n code page selection
+func (b *Buffer) CodePage(cp *CodePage) {
+	b.Write(cp.Select())
+}
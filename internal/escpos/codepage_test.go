@@ -0,0 +1,106 @@
+package escpos
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		page string
+		text string
+		want []byte
+	}{
+		{"cp437", "çãé", []byte{0x87, 'a', 0x82}},
+		{"cp850", "çãé", []byte{0x87, 0xC6, 0x82}},
+		{"cp858", "çãé", []byte{0x87, 0xC6, 0x82}},
+		{"cp860", "çãé", []byte{0x87, 0x84, 0x82}},
+		{"wpc1252", "çãé", []byte{0xE7, 0xE3, 0xE9}},
+
+		{"cp437", "ÇÃÕÁÉÍÓÚÂÊÔÀ", []byte{0x80, 'A', 'O', 'A', 0x90, 'I', 'O', 'U', 'A', 'E', 'O', 'A'}},
+		{"cp850", "ÇÃÕÁÉÍÓÚÂÊÔÀ", []byte{0x80, 0xC7, 0xE5, 0xB5, 0x90, 0xD6, 0xE0, 0xE9, 0xB6, 0xD2, 0xE2, 0xB7}},
+		{"cp858", "ÇÃÕÁÉÍÓÚÂÊÔÀ", []byte{0x80, 0xC7, 0xE5, 0xB5, 0x90, 0xD6, 0xE0, 0xE9, 0xB6, 0xD2, 0xE2, 0xB7}},
+		{"cp860", "ÇÃÕÁÉÍÓÚÂÊÔÀ", []byte{0x80, 0x8E, 0x99, 0x86, 0x90, 0x8B, 0x9F, 0x96, 0x8F, 0x89, 0x8C, 0x91}},
+		{"wpc1252", "ÇÃÕÁÉÍÓÚÂÊÔÀ", []byte{0xC7, 0xC3, 0xD5, 0xC1, 0xC9, 0xCD, 0xD3, 0xDA, 0xC2, 0xCA, 0xD4, 0xC0}},
+
+		{"cp437", "õáíóúâêôà", []byte{'o', 0xA0, 0xA1, 0xA2, 0xA3, 0x83, 0x88, 0x93, 0x85}},
+		{"cp850", "õáíóúâêôà", []byte{0xE4, 0xA0, 0xA1, 0xA2, 0xA3, 0x83, 0x88, 0x93, 0x85}},
+		{"cp860", "õáíóúâêôà", []byte{0x94, 0xA0, 0xA1, 0xA2, 0xA3, 0x83, 0x88, 0x93, 0x85}},
+		{"wpc1252", "õáíóúâêôà", []byte{0xF5, 0xE1, 0xED, 0xF3, 0xFA, 0xE2, 0xEA, 0xF4, 0xE0}},
+
+		{"cp850", "€ºª", []byte{'E', 'U', 'R', 0xA7, 0xA6}},
+		{"cp858", "€ºª", []byte{0xD5, 0xA7, 0xA6}},
+		{"cp860", "€ºª", []byte{'E', 'U', 'R', 0xA7, 0xA6}},
+		{"wpc1252", "€ºª", []byte{0x80, 0xBA, 0xAA}},
+
+		{"cp850", "R$ 10,00\n", []byte("R$ 10,00\n")},
+		{"cp850", "中", []byte{'?'}},
+	}
+	for _, tt := range tests {
+		cp, err := LookupCodePage(tt.page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := cp.Encode(tt.text); !bytes.Equal(got, tt.want) {
+			t.Errorf("%s %q: got % X, want % X", tt.page, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCodePageTables(t *testing.T) {
+	for _, name := range CodePageNames() {
+		cp, _ := LookupCodePage(name)
+		seen := map[rune]byte{}
+		for i := 0x80; i <= 0xFF; i++ {
+			r := cp.Decode(byte(i))
+			if r == '?' {
+				continue
+			}
+			if prev, ok := seen[r]; ok {
+				t.Errorf("%s: %U at 0x%02X and 0x%02X", name, r, prev, i)
+			}
+			seen[r] = byte(i)
+			if got := cp.Encode(string(r)); !bytes.Equal(got, []byte{byte(i)}) {
+				t.Errorf("%s: %U encodes to % X, want %02X", name, r, got, i)
+			}
+		}
+	}
+}
+
+func TestLookupCodePage(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   string
+		number byte
+	}{
+		{"cp437", "cp437", 0},
+		{"PC850", "cp850", 2},
+		{" cp860 ", "cp860", 3},
+		{"windows-1252", "wpc1252", 16},
+		{"pc858", "cp858", 19},
+	}
+	for _, tt := range tests {
+		cp, err := LookupCodePage(tt.name)
+		if err != nil {
+			t.Errorf("%q: %v", tt.name, err)
+			continue
+		}
+		if cp.Name != tt.want || cp.Number != tt.number {
+			t.Errorf("%q: got %s/%d, want %s/%d", tt.name, cp.Name, cp.Number, tt.want, tt.number)
+		}
+	}
+	if _, err := LookupCodePage("latin9"); err == nil {
+		t.Error("unknown code page accepted")
+	}
+}
+
+func TestEncodeText(t *testing.T) {
+	cp, _ := LookupCodePage("cp860")
+	in := append([]byte("ç"), ESC, '@', 0xFF)
+	in = append(in, "ã"...)
+	want := []byte{ESC, 't', 3, 0x87, ESC, '@', ESC, 't', 3, 0xFF, 0x84}
+
+	if got := cp.EncodeText(in); !bytes.Equal(got, want) {
+		t.Errorf("got % X, want % X", got, want)
+	}
+}
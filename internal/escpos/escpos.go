@@ -0,0 +1,265 @@
+package escpos
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/willjrcom/gfood-printer/internal/raster"
+)
+
+// Control bytes
+const (
+	LF  = 0x0A
+	DLE = 0x10
+	EOT = 0x04
+	ESC = 0x1B
+	GS  = 0x1D
+)
+
+// Alignment of text and images
+type Alignment byte
+
+const (
+	AlignLeft   Alignment = 0
+	AlignCenter Alignment = 1
+	AlignRight  Alignment = 2
+)
+
+// ParseAlignment converts "left", "center" or "right" into an Alignment
+func ParseAlignment(s string) Alignment {
+	switch s {
+	case "center":
+		return AlignCenter
+	case "right":
+		return AlignRight
+	default:
+		return AlignLeft
+	}
+}
+
+// QR code error correction levels
+const (
+	QRLevelL byte = 48
+	QRLevelM byte = 49
+	QRLevelQ byte = 50
+	QRLevelH byte = 51
+)
+
+// Image modes: raster (GS v 0) or 24-dot columns (ESC *)
+const (
+	ImageRaster = "raster"
+	ImageColumn = "column"
+)
+
+// Buzzer commands: the built-in buzzer variant of the model or an external
+// buzzer/light unit plugged into the drawer port
+const (
+	BuzzerNone   = "none"
+	BuzzerEscB   = "esc_b"  // ESC B n t: Bematech, Elgin and most kitchen clones
+	BuzzerEpson  = "epson"  // ESC ( A: Epson TM models with the buzzer option
+	BuzzerDrawer = "drawer" // ESC p pulses on the drawer port
+)
+
+// Barcode symbologies (GS k function B)
+const (
+	BarcodeEAN13   byte = 67
+	BarcodeITF     byte = 70
+	BarcodeCode128 byte = 73
+)
+
+// Buffer accumulates an ESC/POS command stream
+type Buffer struct {
+	bytes.Buffer
+}
+
+// Init resets the printer to its power-on state (ESC @)
+func (b *Buffer) Init() {
+	b.Write([]byte{ESC, '@'})
+}
+
+// Align sets the justification (ESC a n)
+func (b *Buffer) Align(a Alignment) {
+	b.Write([]byte{ESC, 'a', byte(a)})
+}
+
+// Bold turns emphasized mode on or off (ESC E n)
+func (b *Buffer) Bold(on bool) {
+	b.Write([]byte{ESC, 'E', boolByte(on)})
+}
+
+// Underline turns underline on or off (ESC - n)
+func (b *Buffer) Underline(on bool) {
+	b.Write([]byte{ESC, '-', boolByte(on)})
+}
+
+// Font selects font A (0) or font B (1) (ESC M n)
+func (b *Buffer) Font(n byte) {
+	b.Write([]byte{ESC, 'M', n})
+}
+
+// Size sets the character magnification, 1 to 8 in each direction (GS ! n)
+func (b *Buffer) Size(width, height int) {
+	w, h := clamp(width, 1, 8)-1, clamp(height, 1, 8)-1
+	b.Write([]byte{GS, '!', byte(w<<4 | h)})
+}
+
+// Text writes raw text bytes; callers are responsible for the code page
+func (b *Buffer) Text(s string) {
+	b.WriteString(s)
+}
+
+// Line writes the text followed by a line feed
+func (b *Buffer) Line(s string) {
+	b.WriteString(s)
+	b.WriteByte(LF)
+}
+
+// Feed prints the buffer and feeds n lines (ESC d n)
+func (b *Buffer) Feed(n int) {
+	b.Write([]byte{ESC, 'd', byte(clamp(n, 0, 255))})
+}
+
+// Cut feeds the paper to the cutter and cuts it (GS V m n)
+func (b *Buffer) Cut(partial bool) {
+	m := byte(65)
+	if partial {
+		m = 66
+	}
+	b.Write([]byte{GS, 'V', m, 3})
+}
+
+// DrawerKick pulses the cash drawer pin (0 = pin 2, 1 = pin 5) with on/off
+// times in milliseconds (ESC p m t1 t2, in 2ms units)
+func (b *Buffer) DrawerKick(pin, onMs, offMs int) {
+	b.Write([]byte{ESC, 'p', byte(clamp(pin, 0, 1)), byte(clamp(onMs/2, 1, 255)), byte(clamp(offMs/2, 1, 255))})
+}
+
+// Beep sounds the buzzer times times for durationMs each with the given
+// command; BuzzerDrawer pulses pin (0 = pin 2, 1 = pin 5) instead. Unknown
+// modes and BuzzerNone write nothing.
+func (b *Buffer) Beep(mode string, times, durationMs, pin int) {
+	times = clamp(times, 1, 9)
+	switch mode {
+	case BuzzerEscB:
+		// t in 50ms units
+		b.Write([]byte{ESC, 'B', byte(times), byte(clamp(durationMs/50, 1, 9))})
+	case BuzzerEpson:
+		// fn 48: pattern m ('1'-'7', from short to long beeps), c times
+		pattern := byte('0' + clamp(durationMs/100+1, 1, 7))
+		b.Write([]byte{ESC, '(', 'A', 4, 0, 48, pattern, byte(times), 0})
+	case BuzzerDrawer:
+		for i := 0; i < times; i++ {
+			b.DrawerKick(pin, durationMs, durationMs)
+		}
+	}
+}
+
+// QRCode prints a QR code with the printer's native model 2 support (GS ( k)
+func (b *Buffer) QRCode(data string, moduleSize int, level byte) error {
+	// The data is stored in byte mode, which holds at most 2953 bytes (40-L)
+	if len(data) == 0 || len(data) > 2953 {
+		return fmt.Errorf("invalid QR code data length: %d", len(data))
+	}
+	// Model 2
+	b.Write([]byte{GS, '(', 'k', 4, 0, 49, 65, 50, 0})
+	// Module size
+	b.Write([]byte{GS, '(', 'k', 3, 0, 49, 67, byte(clamp(moduleSize, 1, 16))})
+	// Error correction level
+	b.Write([]byte{GS, '(', 'k', 3, 0, 49, 69, level})
+	// Store data
+	n := len(data) + 3
+	b.Write([]byte{GS, '(', 'k', byte(n % 256), byte(n / 256), 49, 80, 48})
+	b.WriteString(data)
+	// Print
+	b.Write([]byte{GS, '(', 'k', 3, 0, 49, 81, 48})
+	return nil
+}
+
+// Barcode prints a 1D barcode with the printer's native support (GS k m n d1...dn).
+// height is in dots, width is the module width (2-6) and hri prints the
+// human readable text below the bars.
+func (b *Buffer) Barcode(symbology byte, data string, height, width int, hri bool) error {
+	if len(data) == 0 || len(data) > 253 {
+		return fmt.Errorf("invalid barcode data length: %d", len(data))
+	}
+	if symbology == BarcodeCode128 {
+		// Code set B unless the caller selected one
+		if len(data) < 2 || data[0] != '{' {
+			data = "{B" + data
+		}
+	}
+
+	hriPos := byte(0)
+	if hri {
+		hriPos = 2
+	}
+	b.Write([]byte{GS, 'H', hriPos})
+	b.Write([]byte{GS, 'h', byte(clamp(height, 1, 255))})
+	b.Write([]byte{GS, 'w', byte(clamp(width, 2, 6))})
+	b.Write([]byte{GS, 'k', symbology, byte(len(data))})
+	b.WriteString(data)
+	return nil
+}
+
+// Raster prints a monochrome bitmap (GS v 0)
+func (b *Buffer) Raster(bm *raster.Bitmap) {
+	if bm == nil || bm.Width == 0 || bm.Height == 0 {
+		return
+	}
+	xBytes := bm.RowBytes()
+	b.Write([]byte{GS, 'v', '0', 0, byte(xBytes % 256), byte(xBytes / 256), byte(bm.Height % 256), byte(bm.Height / 256)})
+	b.Write(bm.Packed())
+}
+
+// BitImage prints a monochrome bitmap in 24-dot column format (ESC * 33),
+// for printers without GS v 0
+func (b *Buffer) BitImage(bm *raster.Bitmap) {
+	if bm == nil || bm.Width == 0 || bm.Height == 0 {
+		return
+	}
+	// Line spacing equal to the band height so the bands touch
+	b.Write([]byte{ESC, '3', 24})
+	for top := 0; top < bm.Height; top += 24 {
+		b.Write([]byte{ESC, '*', 33, byte(bm.Width % 256), byte(bm.Width / 256)})
+		for x := 0; x < bm.Width; x++ {
+			for k := 0; k < 3; k++ {
+				var column byte
+				for bit := 0; bit < 8; bit++ {
+					if bm.At(x, top+k*8+bit) {
+						column |= 0x80 >> uint(bit)
+					}
+				}
+				b.WriteByte(column)
+			}
+		}
+		b.WriteByte(LF)
+	}
+	// Default line spacing
+	b.Write([]byte{ESC, '2'})
+}
+
+// Image prints the bitmap with the command of the image mode
+func (b *Buffer) Image(bm *raster.Bitmap, mode string) {
+	if mode == ImageColumn {
+		b.BitImage(bm)
+		return
+	}
+	b.Raster(bm)
+}
+
+func boolByte(on bool) byte {
+	if on {
+		return 1
+	}
+	return 0
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,181 @@
+package escpos
+
+import (
+	"sort"
+	"strings"
+)
+
+// Cutter capabilities
+const (
+	CutPartial = "partial" // full and partial cuts
+	CutFull    = "full"    // full cut only
+	CutNone    = "none"    // no cutter, the paper is torn off
+)
+
+// Profile describes the paper geometry and the supported commands of a printer
+type Profile struct {
+	Name         string `json:"name"`
+	Model        string `json:"model,omitempty"`
+	PaperWidth   int    `json:"paper_width"`    // mm
+	DotWidth     int    `json:"dot_width"`      // printable dots per line
+	ColumnsFontA int    `json:"columns_font_a"` // characters per line in font A (12x24)
+	ColumnsFontB int    `json:"columns_font_b"` // characters per line in font B (9x17)
+
+	// Native symbol support; without it QR codes and barcodes are sent as raster images
+	NativeQR      bool `json:"native_qr"`      // GS ( k
+	NativeBarcode bool `json:"native_barcode"` // GS k
+
+	// ImageMode is the command used for bitmaps: ImageRaster or ImageColumn
+	ImageMode string `json:"image_mode"`
+
+	// Cut is the cutter capability: CutPartial, CutFull or CutNone
+	Cut string `json:"cut"`
+
+	// CodePage is the default character table; CodePages maps the supported
+	// tables to their ESC t numbers when they differ from Epson's
+	CodePage  string         `json:"code_page,omitempty"`
+	CodePages map[string]int `json:"code_pages,omitempty"`
+
+	// Buzzer is the command used for alerts: BuzzerEscB, BuzzerEpson,
+	// BuzzerDrawer or empty/BuzzerNone when the model has none
+	Buzzer string `json:"buzzer,omitempty"`
+
+	// Match lists lowercase fragments of system printer names that identify the model
+	Match []string `json:"match,omitempty"`
+}
+
+// PaperProfile returns the usual geometry for 58mm or 80mm rolls
+func PaperProfile(paperWidth int) Profile {
+	if paperWidth == 58 {
+		return profiles["generic-58"]
+	}
+	return profiles["generic-80"]
+}
+
+// profiles is the built-in catalog
+var profiles = map[string]Profile{
+	"generic-80": {
+		Name: "generic-80", Model: "Generic 80mm",
+		PaperWidth: 80, DotWidth: 576, ColumnsFontA: 48, ColumnsFontB: 64,
+		NativeQR: true, NativeBarcode: true, ImageMode: ImageRaster, Cut: CutPartial,
+	},
+	// 58mm clones usually lack GS ( k; without a cutter GS V is ignored
+	"generic-58": {
+		Name: "generic-58", Model: "Generic 58mm",
+		PaperWidth: 58, DotWidth: 384, ColumnsFontA: 32, ColumnsFontB: 42,
+		NativeQR: false, NativeBarcode: true, ImageMode: ImageRaster, Cut: CutPartial,
+		Match: []string{"58mm", "pos-58", "pos58", "xp-58"},
+	},
+	"epson-tm-t20": {
+		Name: "epson-tm-t20", Model: "Epson TM-T20",
+		PaperWidth: 80, DotWidth: 576, ColumnsFontA: 48, ColumnsFontB: 64,
+		NativeQR: true, NativeBarcode: true, ImageMode: ImageRaster, Cut: CutPartial,
+		CodePage: "cp850",
+		Buzzer:   BuzzerEpson,
+		Match:    []string{"tm-t20", "tm_t20", "tmt20"},
+	},
+	// MP-4200 TH in ESC/POS mode; 72mm printable area
+	"bematech-mp4200": {
+		Name: "bematech-mp4200", Model: "Bematech MP-4200 TH",
+		PaperWidth: 80, DotWidth: 576, ColumnsFontA: 48, ColumnsFontB: 64,
+		NativeQR: true, NativeBarcode: true, ImageMode: ImageRaster, Cut: CutPartial,
+		CodePage: "cp850",
+		Buzzer:   BuzzerEscB,
+		Match:    []string{"mp-4200", "mp4200", "mp_4200"},
+	},
+	"elgin-i9": {
+		Name: "elgin-i9", Model: "Elgin i9",
+		PaperWidth: 80, DotWidth: 576, ColumnsFontA: 48, ColumnsFontB: 64,
+		NativeQR: true, NativeBarcode: true, ImageMode: ImageRaster, Cut: CutPartial,
+		CodePage: "cp850",
+		Buzzer:   BuzzerEscB,
+		Match:    []string{"elgin i9", "elgin_i9", "elgin-i9"},
+	},
+	// DR800 emulating ESC/POS: no GS ( k and only column bit images
+	"daruma-dr800": {
+		Name: "daruma-dr800", Model: "Daruma DR800",
+		PaperWidth: 80, DotWidth: 576, ColumnsFontA: 48, ColumnsFontB: 64,
+		NativeQR: false, NativeBarcode: true, ImageMode: ImageColumn, Cut: CutFull,
+		CodePage: "cp850",
+		Match:    []string{"daruma", "dr800", "dr-800"},
+	},
+}
+
+// LookupProfile finds a built-in profile by name
+func LookupProfile(name string) (Profile, bool) {
+	p, ok := profiles[strings.ToLower(strings.TrimSpace(name))]
+	return p, ok
+}
+
+// Profiles returns the built-in catalog sorted by name
+func Profiles() []Profile {
+	list := make([]Profile, 0, len(profiles))
+	for _, p := range profiles {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// DetectProfile guesses the built-in profile from the system printer name
+func DetectProfile(printerName string) (Profile, bool) {
+	name := strings.ToLower(printerName)
+	for _, p := range Profiles() {
+		for _, m := range p.Match {
+			if strings.Contains(name, m) {
+				return p, true
+			}
+		}
+	}
+	return Profile{}, false
+}
+
+// WithDefaults fills the zero fields of a user-defined profile from the
+// generic profile of its paper width
+func (p Profile) WithDefaults() Profile {
+	base := PaperProfile(p.PaperWidth)
+	if p.PaperWidth == 0 {
+		p.PaperWidth = base.PaperWidth
+	}
+	if p.DotWidth == 0 {
+		p.DotWidth = base.DotWidth
+	}
+	if p.ColumnsFontA == 0 {
+		p.ColumnsFontA = base.ColumnsFontA
+	}
+	if p.ColumnsFontB == 0 {
+		p.ColumnsFontB = base.ColumnsFontB
+	}
+	if p.ImageMode == "" {
+		p.ImageMode = ImageRaster
+	}
+	if p.Cut == "" {
+		p.Cut = CutPartial
+	}
+	return p
+}
+
+// CodePageNumber returns the ESC t number of the table on this printer
+func (p Profile) CodePageNumber(cp *CodePage) byte {
+	if n, ok := p.CodePages[cp.Name]; ok {
+		return byte(n)
+	}
+	return cp.Number
+}
+
+// Columns returns the characters per line of the font ("a" or "b")
+func (p Profile) Columns(font string) int {
+	if font == "b" {
+		return p.ColumnsFontB
+	}
+	return p.ColumnsFontA
+}
+
+// ValidBuzzer reports whether mode is a known buzzer command
+func ValidBuzzer(mode string) bool {
+	switch mode {
+	case "", BuzzerNone, BuzzerEscB, BuzzerEpson, BuzzerDrawer:
+		return true
+	}
+	return false
+}
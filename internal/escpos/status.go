@@ -0,0 +1,112 @@
+package escpos
+
+import "fmt"
+
+// Real-time status requests (DLE EOT n)
+const (
+	StatusPrinter      = 1 // online/offline
+	StatusOfflineCause = 2 // cause of the offline state
+	StatusErrorCause   = 3 // error cause
+	StatusPaperSensor  = 4 // paper roll sensor
+)
+
+// Printer states, from the most to the least severe
+const (
+	StateOffline      = "offline"
+	StateCoverOpen    = "cover_open"
+	StatePaperOut     = "paper_out"
+	StateCutterError  = "cutter_error"
+	StateError        = "error"
+	StatePaperNearEnd = "paper_near_end"
+	StateReady        = "ready"
+)
+
+// Status is the decoded real-time status of a printer
+type Status struct {
+	Offline            bool `json:"offline"`
+	CoverOpen          bool `json:"cover_open"`
+	PaperOut           bool `json:"paper_out"`
+	PaperNearEnd       bool `json:"paper_near_end"`
+	CutterError        bool `json:"cutter_error"`
+	UnrecoverableError bool `json:"unrecoverable_error"`
+	RecoverableError   bool `json:"recoverable_error"`
+}
+
+// StatusRequest returns the DLE EOT n command
+func StatusRequest(n byte) []byte {
+	return []byte{DLE, EOT, n}
+}
+
+// PaperStatusRequest returns GS r 1. Unlike DLE EOT it is processed in order
+// with the print data, so its answer also confirms that the job was printed.
+func PaperStatusRequest() []byte {
+	return []byte{GS, 'r', 1}
+}
+
+// validStatusByte checks the fixed bits of a DLE EOT answer (bits 1 and 4
+// set, bits 0 and 7 clear)
+func validStatusByte(b byte) bool {
+	return b&0x93 == 0x12
+}
+
+// ParseStatus decodes the answers to DLE EOT 1, 2, 3 and 4
+func ParseStatus(printer, offline, errCause, paper byte) (Status, error) {
+	for n, b := range []byte{printer, offline, errCause, paper} {
+		if !validStatusByte(b) {
+			return Status{}, fmt.Errorf("invalid answer to DLE EOT %d: 0x%02X", n+1, b)
+		}
+	}
+	return Status{
+		Offline:            printer&0x08 != 0,
+		CoverOpen:          offline&0x04 != 0,
+		PaperOut:           offline&0x20 != 0 || paper&0x60 != 0,
+		PaperNearEnd:       paper&0x0C != 0,
+		CutterError:        errCause&0x08 != 0,
+		UnrecoverableError: errCause&0x20 != 0,
+		RecoverableError:   errCause&0x40 != 0,
+	}, nil
+}
+
+// ParsePaperStatus decodes the answer to GS r 1 into the paper flags
+func ParsePaperStatus(b byte) (nearEnd, out bool) {
+	return b&0x03 != 0, b&0x0C != 0
+}
+
+// State returns the most severe condition of the printer
+func (s Status) State() string {
+	switch {
+	case s.CoverOpen:
+		return StateCoverOpen
+	case s.PaperOut:
+		return StatePaperOut
+	case s.CutterError:
+		return StateCutterError
+	case s.UnrecoverableError, s.RecoverableError:
+		return StateError
+	case s.Offline:
+		return StateOffline
+	case s.PaperNearEnd:
+		return StatePaperNearEnd
+	default:
+		return StateReady
+	}
+}
+
+// Err returns an error when the printer cannot print; paper near end still prints
+func (s Status) Err() error {
+	switch state := s.State(); state {
+	case StateReady, StatePaperNearEnd:
+		return nil
+	default:
+		return &StatusError{State: state}
+	}
+}
+
+// StatusError reports a printer that cannot print
+type StatusError struct {
+	State string
+}
+
+func (e *StatusError) Error() string {
+	return "printer status: " + e.State
+}
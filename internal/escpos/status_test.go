@@ -0,0 +1,75 @@
+package escpos
+
+import "testing"
+
+func TestParseStatus(t *testing.T) {
+	// 0x12 is an answer with only the fixed bits set
+	tests := []struct {
+		name                              string
+		printer, offline, errCause, paper byte
+		want                              Status
+		state                             string
+	}{
+		{"ready", 0x12, 0x12, 0x12, 0x12, Status{}, StateReady},
+		{"offline", 0x1A, 0x12, 0x12, 0x12, Status{Offline: true}, StateOffline},
+		{"cover open", 0x1A, 0x16, 0x12, 0x12, Status{Offline: true, CoverOpen: true}, StateCoverOpen},
+		{"paper end stop", 0x12, 0x32, 0x12, 0x12, Status{PaperOut: true}, StatePaperOut},
+		{"paper end sensor", 0x12, 0x12, 0x12, 0x72, Status{PaperOut: true}, StatePaperOut},
+		{"paper near end", 0x12, 0x12, 0x12, 0x1E, Status{PaperNearEnd: true}, StatePaperNearEnd},
+		{"cutter error", 0x12, 0x12, 0x1A, 0x12, Status{CutterError: true}, StateCutterError},
+		{"unrecoverable error", 0x12, 0x12, 0x32, 0x12, Status{UnrecoverableError: true}, StateError},
+		{"recoverable error", 0x12, 0x12, 0x52, 0x12, Status{RecoverableError: true}, StateError},
+		{"feed button and error bits ignored", 0x12, 0x5A, 0x12, 0x12, Status{}, StateReady},
+	}
+	for _, tt := range tests {
+		got, err := ParseStatus(tt.printer, tt.offline, tt.errCause, tt.paper)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+		if state := got.State(); state != tt.state {
+			t.Errorf("%s: state %s, want %s", tt.name, state, tt.state)
+		}
+		ok := tt.state == StateReady || tt.state == StatePaperNearEnd
+		if err := got.Err(); (err == nil) != ok {
+			t.Errorf("%s: Err() = %v", tt.name, err)
+		}
+	}
+}
+
+func TestParseStatusInvalid(t *testing.T) {
+	for _, b := range []byte{0x00, 0x02, 0x10, 0x13, 0x92, 0xFF} {
+		for n := 0; n < 4; n++ {
+			answers := []byte{0x12, 0x12, 0x12, 0x12}
+			answers[n] = b
+			if _, err := ParseStatus(answers[0], answers[1], answers[2], answers[3]); err == nil {
+				t.Errorf("0x%02X accepted as answer %d", b, n+1)
+			}
+		}
+	}
+}
+
+func TestParsePaperStatus(t *testing.T) {
+	tests := []struct {
+		b            byte
+		nearEnd, out bool
+	}{
+		{0x00, false, false},
+		{0x01, true, false},
+		{0x02, true, false},
+		{0x03, true, false},
+		{0x04, false, true},
+		{0x08, false, true},
+		{0x0F, true, true},
+		{0x10, false, false},
+	}
+	for _, tt := range tests {
+		nearEnd, out := ParsePaperStatus(tt.b)
+		if nearEnd != tt.nearEnd || out != tt.out {
+			t.Errorf("0x%02X: near end %v out %v, want %v %v", tt.b, nearEnd, out, tt.nearEnd, tt.out)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package preview
+
+import (
+	"image"
+	"image/color"
+)
+
+// Dot values on the canvas
+const (
+	dotWhite uint8 = iota
+	dotBlack
+	dotMark // annotations such as cut marks, drawn in gray
+)
+
+// maxHeight stops runaway streams from allocating huge images (about 3.7m of paper)
+const maxHeight = 30000
+
+// canvas is a strip of paper that grows as lines are printed
+type canvas struct {
+	width int
+	rows  [][]uint8
+}
+
+func (c *canvas) set(x, y int, v uint8) {
+	if x < 0 || x >= c.width || y < 0 || y >= maxHeight {
+		return
+	}
+	for len(c.rows) <= y {
+		c.rows = append(c.rows, make([]uint8, c.width))
+	}
+	c.rows[y][x] = v
+}
+
+func (c *canvas) fill(x, y, w, h int, v uint8) {
+	for dy := 0; dy < h; dy++ {
+		for dx := 0; dx < w; dx++ {
+			c.set(x+dx, y+dy, v)
+		}
+	}
+}
+
+// image converts the canvas to grayscale with at least height rows
+func (c *canvas) image(height int) *image.Gray {
+	if height < len(c.rows) {
+		height = len(c.rows)
+	}
+	if height > maxHeight {
+		height = maxHeight
+	}
+	img := image.NewGray(image.Rect(0, 0, c.width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	for y, row := range c.rows {
+		for x, v := range row {
+			switch v {
+			case dotBlack:
+				img.SetGray(x, y, color.Gray{Y: 0})
+			case dotMark:
+				img.SetGray(x, y, color.Gray{Y: 160})
+			}
+		}
+	}
+	return img
+}
@@ -0,0 +1,706 @@
+// Package preview interprets an ESC/POS byte stream and draws the ticket the
+// printer would produce, so it can be inspected without the printer.
+package preview
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"unicode/utf8"
+
+	"github.com/willjrcom/gfood-printer/internal/barcode"
+	"github.com/willjrcom/gfood-printer/internal/escpos"
+)
+
+// Options describes the emulated printer
+type Options struct {
+	DotWidth int // printable width in dots
+
+	// CodePage is the table active after power on or ESC @; nil means cp437
+	CodePage *escpos.CodePage
+}
+
+// Character cells in dots
+const (
+	fontAWidth, fontAHeight = 12, 24
+	fontBWidth, fontBHeight = 9, 17
+
+	defaultLineSpacing = 30
+	marginBottom       = 16
+)
+
+// Render interprets the stream and returns the printed ticket
+func Render(data []byte, opts Options) image.Image {
+	if opts.DotWidth <= 0 {
+		opts.DotWidth = 576
+	}
+	if opts.CodePage == nil {
+		opts.CodePage, _ = escpos.LookupCodePage("cp437")
+	}
+
+	in := &interpreter{opts: opts, data: data, paper: canvas{width: opts.DotWidth}}
+	in.reset()
+	in.run()
+	in.flush(false)
+	return in.paper.image(in.y + marginBottom)
+}
+
+// PNG renders the stream and encodes the ticket as PNG
+func PNG(data []byte, opts Options) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, Render(data, opts)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// style is the character formatting in effect
+type style struct {
+	fontB     bool
+	bold      bool
+	underline int
+	width     int // 1-8
+	height    int // 1-8
+	reverse   bool
+}
+
+// item is something waiting in the line buffer
+type item struct {
+	width, height int
+	draw          func(x, bottom int)
+}
+
+type interpreter struct {
+	opts  Options
+	data  []byte
+	pos   int
+	paper canvas
+	y     int
+
+	style       style
+	align       escpos.Alignment
+	lineSpacing int
+	codePage    *escpos.CodePage
+
+	line      []item
+	lineWidth int
+
+	barHeight, barWidth, hri int
+	qrSize                   int
+	qrLevel                  byte
+	qrData                   []byte
+}
+
+// reset restores the power on state (ESC @)
+func (in *interpreter) reset() {
+	in.style = style{width: 1, height: 1}
+	in.align = escpos.AlignLeft
+	in.lineSpacing = defaultLineSpacing
+	in.codePage = in.opts.CodePage
+	in.barHeight, in.barWidth, in.hri = 162, 3, 0
+	in.qrSize, in.qrLevel, in.qrData = 3, escpos.QRLevelL, nil
+}
+
+// take returns the next n bytes, or false when the stream ends early
+func (in *interpreter) take(n int) ([]byte, bool) {
+	if n < 0 || in.pos+n > len(in.data) {
+		in.pos = len(in.data)
+		return nil, false
+	}
+	b := in.data[in.pos : in.pos+n]
+	in.pos += n
+	return b, true
+}
+
+func (in *interpreter) byte1() (byte, bool) {
+	b, ok := in.take(1)
+	if !ok {
+		return 0, false
+	}
+	return b[0], true
+}
+
+func (in *interpreter) skip(n int) {
+	in.take(n)
+}
+
+func (in *interpreter) run() {
+	for in.pos < len(in.data) {
+		b := in.data[in.pos]
+		in.pos++
+		switch {
+		case b == escpos.LF:
+			in.flush(true)
+		case b == '\r':
+		case b == '\t':
+			in.tab()
+		case b == 0x0C: // FF
+			in.flush(false)
+		case b == escpos.ESC:
+			in.esc()
+		case b == escpos.GS:
+			in.gs()
+		case b == escpos.DLE:
+			in.dle()
+		case b == 0x1C: // FS
+			in.fs()
+		case b < 0x20 || b == 0x7F:
+			// Other control codes print nothing
+		default:
+			in.char(in.codePage.Decode(b))
+		}
+	}
+}
+
+func (in *interpreter) esc() {
+	cmd, ok := in.byte1()
+	if !ok {
+		return
+	}
+	switch cmd {
+	case '@':
+		in.flush(false)
+		in.reset()
+	case '!':
+		n, _ := in.byte1()
+		in.style.fontB = n&0x01 != 0
+		in.style.bold = n&0x08 != 0
+		in.style.height = 1 + int(n>>4&1)
+		in.style.width = 1 + int(n>>5&1)
+		in.style.underline = int(n >> 7 & 1)
+	case 'E', 'G':
+		n, _ := in.byte1()
+		in.style.bold = n&1 != 0
+	case '-':
+		n, _ := in.byte1()
+		in.style.underline = int(n % 48)
+		if in.style.underline > 2 {
+			in.style.underline = 0
+		}
+	case 'M':
+		n, _ := in.byte1()
+		in.style.fontB = n%48 == 1
+	case 'a':
+		n, _ := in.byte1()
+		in.align = escpos.Alignment(n % 48)
+	case 't':
+		n, _ := in.byte1()
+		if n == in.opts.CodePage.Number {
+			in.codePage = in.opts.CodePage
+		} else if cp, found := escpos.LookupCodePageNumber(n); found {
+			in.codePage = cp
+		}
+	case 'd':
+		n, _ := in.byte1()
+		in.flush(false)
+		in.y += int(n) * in.lineSpacing
+	case 'J':
+		n, _ := in.byte1()
+		in.flush(false)
+		in.y += int(n)
+	case '2':
+		in.lineSpacing = defaultLineSpacing
+	case '3':
+		n, _ := in.byte1()
+		in.lineSpacing = int(n)
+	case '*':
+		in.bitImage()
+	case 'i', 'm':
+		in.flush(false)
+		in.cutMark(cmd == 'm')
+	case 'p':
+		in.skip(3) // cash drawer
+	case 'c':
+		in.skip(2)
+	case '$', '\\':
+		in.skip(2)
+	case 'D':
+		// Tab positions, NUL terminated
+		for {
+			b, ok := in.byte1()
+			if !ok || b == 0 {
+				break
+			}
+		}
+	case ' ', 'R', 'V', '{', '=', 'r', 'U', 'T', 'e', 'K':
+		in.skip(1)
+	}
+}
+
+func (in *interpreter) gs() {
+	cmd, ok := in.byte1()
+	if !ok {
+		return
+	}
+	switch cmd {
+	case '!':
+		n, _ := in.byte1()
+		in.style.width = 1 + int(n>>4&7)
+		in.style.height = 1 + int(n&7)
+	case 'B':
+		n, _ := in.byte1()
+		in.style.reverse = n&1 != 0
+	case 'V':
+		m, _ := in.byte1()
+		in.flush(false)
+		if m == 65 || m == 66 {
+			n, _ := in.byte1()
+			in.y += int(n)
+		} else if m == 97 || m == 98 || m == 103 || m == 104 {
+			in.skip(1)
+		}
+		in.cutMark(m == 1 || m == 49 || m == 66 || m == 98 || m == 104)
+	case 'v':
+		in.rasterImage()
+	case 'k':
+		in.barcode()
+	case 'h':
+		n, _ := in.byte1()
+		in.barHeight = int(n)
+	case 'w':
+		n, _ := in.byte1()
+		in.barWidth = int(n)
+	case 'H':
+		n, _ := in.byte1()
+		in.hri = int(n % 48)
+	case '(':
+		in.gsParen()
+	case '8':
+		// GS 8 L: 32-bit length graphics
+		sub, _ := in.byte1()
+		p, ok := in.take(4)
+		if sub == 'L' && ok {
+			in.skip(int(p[0]) | int(p[1])<<8 | int(p[2])<<16 | int(p[3])<<24)
+		}
+	case '*':
+		xy, ok := in.take(2)
+		if ok {
+			in.skip(int(xy[0]) * int(xy[1]) * 8)
+		}
+	case 'L', 'W', 'P', '\\', '$':
+		in.skip(2)
+	case 'f', 'a', 'r', 'I', '/', 'b', 'E':
+		in.skip(1)
+	}
+}
+
+// gsParen handles GS ( fn pL pH ...; only QR codes (GS ( k cn=49) are drawn
+func (in *interpreter) gsParen() {
+	fn, _ := in.byte1()
+	p, ok := in.take(2)
+	if !ok {
+		return
+	}
+	params, ok := in.take(int(p[0]) | int(p[1])<<8)
+	if !ok || fn != 'k' || len(params) < 2 || params[0] != 49 {
+		return
+	}
+
+	switch params[1] {
+	case 67: // module size
+		if len(params) > 2 {
+			in.qrSize = int(params[2])
+		}
+	case 69: // error correction
+		if len(params) > 2 {
+			in.qrLevel = params[2]
+		}
+	case 80: // store data
+		if len(params) > 3 {
+			in.qrData = append([]byte{}, params[3:]...)
+		}
+	case 81: // print
+		in.flush(false)
+		in.qrCode()
+	}
+}
+
+func (in *interpreter) dle() {
+	cmd, ok := in.byte1()
+	if !ok {
+		return
+	}
+	switch cmd {
+	case escpos.EOT, 0x05: // status requests
+		in.skip(1)
+	case 0x14:
+		in.skip(3)
+	}
+}
+
+func (in *interpreter) fs() {
+	cmd, ok := in.byte1()
+	if !ok {
+		return
+	}
+	switch cmd {
+	case 'p', 'S':
+		in.skip(2) // NV bit image, kanji spacing
+	case '!', '-', 'C':
+		in.skip(1)
+	}
+}
+
+// flush prints the line buffer; on LF an empty buffer still feeds one line
+func (in *interpreter) flush(lineFeed bool) {
+	if len(in.line) == 0 {
+		if lineFeed {
+			in.y += in.lineSpacing
+		}
+		return
+	}
+
+	height := 0
+	for _, it := range in.line {
+		if it.height > height {
+			height = it.height
+		}
+	}
+	x := in.alignedX(in.lineWidth)
+	bottom := in.y + height
+	for _, it := range in.line {
+		it.draw(x, bottom)
+		x += it.width
+	}
+
+	if height < in.lineSpacing {
+		height = in.lineSpacing
+	}
+	in.y += height
+	in.line, in.lineWidth = nil, 0
+}
+
+// alignedX returns the left position of something width dots wide
+func (in *interpreter) alignedX(width int) int {
+	switch in.align {
+	case escpos.AlignCenter:
+		return (in.opts.DotWidth - width) / 2
+	case escpos.AlignRight:
+		return in.opts.DotWidth - width
+	default:
+		return 0
+	}
+}
+
+// add appends to the line buffer, wrapping when the line is full
+func (in *interpreter) add(it item) {
+	if in.lineWidth > 0 && in.lineWidth+it.width > in.opts.DotWidth {
+		in.flush(false)
+	}
+	in.line = append(in.line, it)
+	in.lineWidth += it.width
+}
+
+func (in *interpreter) cellSize(st style) (int, int) {
+	if st.fontB {
+		return fontBWidth * st.width, fontBHeight * st.height
+	}
+	return fontAWidth * st.width, fontAHeight * st.height
+}
+
+func (in *interpreter) char(r rune) {
+	st := in.style
+	w, h := in.cellSize(st)
+	in.add(item{width: w, height: h, draw: func(x, bottom int) {
+		in.drawChar(r, st, x, bottom-h)
+	}})
+}
+
+func (in *interpreter) tab() {
+	step := fontAWidth * 8
+	w := step - in.lineWidth%step
+	in.add(item{width: w, height: 0, draw: func(int, int) {}})
+}
+
+// drawChar draws the glyph scaled into the character cell
+func (in *interpreter) drawChar(r rune, st style, x, top int) {
+	cellW, cellH := in.cellSize(st)
+	glyph, mark, upper := glyphFor(r)
+
+	// Glyph box inside the cell: 5x11 virtual dots (accents, 8 glyph rows, cedilla)
+	boxX, boxY := 1*st.width, 1*st.height
+	boxW, boxH := cellW-2*st.width, cellH-2*st.height
+
+	fg := dotBlack
+	if st.reverse {
+		in.paper.fill(x, top, cellW, cellH, dotBlack)
+		fg = dotWhite
+	}
+	for ty := 0; ty < boxH; ty++ {
+		vy := ty * 11 / boxH
+		for tx := 0; tx < boxW; tx++ {
+			vx := tx * 5 / boxW
+			if glyphDot(glyph, mark, upper, vx, vy) {
+				in.paper.set(x+boxX+tx, top+boxY+ty, fg)
+				if st.bold {
+					in.paper.set(x+boxX+tx+1, top+boxY+ty, fg)
+				}
+			}
+		}
+	}
+	if st.underline > 0 {
+		in.paper.fill(x, top+cellH-st.underline, cellW, st.underline, fg)
+	}
+}
+
+// glyphFor returns the columns of the character, its accent and whether the
+// base letter is uppercase
+func glyphFor(r rune) ([5]byte, int, bool) {
+	mark := accentNone
+	if a, ok := accented[r]; ok {
+		r, mark = a.base, a.mark
+	} else if r >= 0x80 {
+		if ascii := escpos.Transliterate(r); ascii != "" {
+			r = rune(ascii[0])
+		}
+	}
+	if r < 0x20 || r > 0x7E {
+		r = '?'
+	}
+	glyph := font5x8[r-0x20]
+	if mark != accentNone && (r == 'i' || r == 'j') {
+		// Dotless base under the accent
+		for i := range glyph {
+			glyph[i] &^= 0x01
+		}
+	}
+	return glyph, mark, r >= 'A' && r <= 'Z'
+}
+
+// glyphDot reports whether the virtual dot is set: rows 0-1 hold accents of
+// uppercase letters, rows 2-9 the glyph and rows 9-10 the cedilla
+func glyphDot(glyph [5]byte, mark int, upper bool, vx, vy int) bool {
+	if vy >= 2 && vy <= 9 && glyph[vx]>>uint(vy-2)&1 != 0 {
+		return true
+	}
+	if mark == accentNone {
+		return false
+	}
+	markRow := -1
+	switch {
+	case mark == accentCedilla:
+		markRow = vy - 9
+	case upper:
+		markRow = vy
+	default:
+		markRow = vy - 2 // lowercase letters leave glyph rows 0-1 free
+	}
+	if markRow < 0 || markRow > 1 {
+		return false
+	}
+	return accentMarks[mark][vx]>>uint(markRow)&1 != 0
+}
+
+// cutMark draws a dashed line where the paper is cut
+func (in *interpreter) cutMark(partial bool) {
+	y := in.y + 6
+	dash, gap := 12, 6
+	if partial {
+		dash = 4
+	}
+	for x := 0; x < in.opts.DotWidth; x += dash + gap {
+		in.paper.fill(x, y, dash, 2, dotMark)
+	}
+	in.y += 14
+}
+
+// blit draws the bitmap rows with horizontal and vertical scale factors
+func (in *interpreter) blit(dots func(x, y int) bool, w, h, x0, y0, sx, sy int) {
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if dots(x, y) {
+				in.paper.fill(x0+x*sx, y0+y*sy, sx, sy, dotBlack)
+			}
+		}
+	}
+}
+
+// rasterImage draws GS v 0 m xL xH yL yH d1...dk
+func (in *interpreter) rasterImage() {
+	head, ok := in.take(6)
+	if !ok || head[0] != '0' {
+		return
+	}
+	m := head[1] % 48
+	rowBytes := int(head[2]) | int(head[3])<<8
+	rows := int(head[4]) | int(head[5])<<8
+	pix, ok := in.take(rowBytes * rows)
+	if !ok {
+		return
+	}
+
+	sx, sy := 1, 1
+	if m&1 != 0 {
+		sx = 2
+	}
+	if m&2 != 0 {
+		sy = 2
+	}
+	in.flush(false)
+	x0 := in.alignedX(rowBytes * 8 * sx)
+	in.blit(func(x, y int) bool {
+		return pix[y*rowBytes+x/8]&(0x80>>uint(x%8)) != 0
+	}, rowBytes*8, rows, x0, in.y, sx, sy)
+	in.y += rows * sy
+}
+
+// bitImage adds an ESC * m nL nH d1...dk band to the line buffer
+func (in *interpreter) bitImage() {
+	head, ok := in.take(3)
+	if !ok {
+		return
+	}
+	m := head[0]
+	columns := int(head[1]) | int(head[2])<<8
+	bytesPerColumn, sx, sy := 1, 2, 2
+	switch m {
+	case 1:
+		sx = 1
+	case 32:
+		bytesPerColumn, sy = 3, 1
+	case 33:
+		bytesPerColumn, sx, sy = 3, 1, 1
+	}
+	data, ok := in.take(columns * bytesPerColumn)
+	if !ok {
+		return
+	}
+
+	dots := bytesPerColumn * 8
+	in.add(item{width: columns * sx, height: dots * sy, draw: func(x, bottom int) {
+		in.blit(func(cx, cy int) bool {
+			return data[cx*bytesPerColumn+cy/8]&(0x80>>uint(cy%8)) != 0
+		}, columns, dots, x, bottom-dots*sy, sx, sy)
+	}})
+}
+
+// qrCode draws the stored QR code symbol
+func (in *interpreter) qrCode() {
+	level := int(in.qrLevel) - int(escpos.QRLevelL)
+	if level < barcode.QRLevelL || level > barcode.QRLevelH {
+		level = barcode.QRLevelL
+	}
+	code, err := barcode.EncodeQR(in.qrData, level)
+	if err != nil || len(in.qrData) == 0 {
+		in.placeholder("QR code inválido")
+		return
+	}
+	size := in.qrSize
+	if size < 1 {
+		size = 1
+	}
+	side := code.Size * size
+	in.blit(func(x, y int) bool { return code.Modules[y][x] }, code.Size, code.Size, in.alignedX(side), in.y, size, size)
+	in.y += side
+}
+
+// barcode draws GS k in function A (NUL terminated) or B (with length)
+func (in *interpreter) barcode() {
+	m, ok := in.byte1()
+	if !ok {
+		return
+	}
+	var data []byte
+	if m <= 6 {
+		start := in.pos
+		for in.pos < len(in.data) && in.data[in.pos] != 0 {
+			in.pos++
+		}
+		data = in.data[start:in.pos]
+		in.skip(1)
+	} else {
+		n, _ := in.byte1()
+		if data, ok = in.take(int(n)); !ok {
+			return
+		}
+	}
+	in.flush(false)
+
+	text := string(data)
+	var code *barcode.Linear
+	var err error
+	switch m {
+	case 2, 67:
+		code, err = barcode.EAN13(text)
+	case 5, 70:
+		code, err = barcode.ITF(text)
+	case 73:
+		text = code128Text(data)
+		code, err = barcode.Code128(text)
+	default:
+		err = fmt.Errorf("symbology %d", m)
+	}
+	if err != nil {
+		in.placeholder("código de barras: " + text)
+		return
+	}
+
+	width := in.barWidth
+	if width < 1 {
+		width = 1
+	}
+	if in.hri == 1 || in.hri == 3 {
+		in.centeredText(code.Text)
+	}
+	total := len(code.Modules) * width
+	in.blit(func(x, y int) bool { return code.Modules[x] }, len(code.Modules), 1, in.alignedX(total), in.y, width, in.barHeight)
+	in.y += in.barHeight
+	if in.hri == 2 || in.hri == 3 {
+		in.centeredText(code.Text)
+	}
+}
+
+// code128Text removes the code set selectors of GS k CODE128 data; code set
+// C pairs become their digits
+func code128Text(data []byte) string {
+	var out []byte
+	setC := false
+	for i := 0; i < len(data); i++ {
+		if data[i] == '{' && i+1 < len(data) {
+			i++
+			switch data[i] {
+			case 'C':
+				setC = true
+			case 'A', 'B':
+				setC = false
+			case '{':
+				out = append(out, '{')
+			}
+			continue
+		}
+		if setC {
+			out = append(out, fmt.Sprintf("%02d", data[i])...)
+		} else {
+			out = append(out, data[i])
+		}
+	}
+	return string(out)
+}
+
+// centeredText prints a line of plain text aligned like the symbols
+func (in *interpreter) centeredText(s string) {
+	st := style{width: 1, height: 1}
+	x := in.alignedX(utf8.RuneCountInString(s) * fontAWidth)
+	for _, r := range s {
+		in.drawChar(r, st, x, in.y)
+		x += fontAWidth
+	}
+	in.y += fontAHeight
+}
+
+// placeholder marks a symbol that could not be drawn
+func (in *interpreter) placeholder(label string) {
+	w := utf8.RuneCountInString(label)*fontAWidth + 16
+	if w > in.opts.DotWidth {
+		w = in.opts.DotWidth
+	}
+	x := in.alignedX(w)
+	in.paper.fill(x, in.y, w, 2, dotMark)
+	in.paper.fill(x, in.y+fontAHeight+10, w, 2, dotMark)
+	in.paper.fill(x, in.y, 2, fontAHeight+12, dotMark)
+	in.paper.fill(x+w-2, in.y, 2, fontAHeight+12, dotMark)
+	in.y += 6
+	in.centeredText(label)
+	in.y += 6
+}
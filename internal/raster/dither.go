@@ -0,0 +1,109 @@
+package raster
+
+import (
+	"fmt"
+	"image"
+	"strings"
+)
+
+// Dither selects how gray levels are converted to black and white dots
+type Dither string
+
+// Dithering methods
+const (
+	DitherThreshold      Dither = "threshold"
+	DitherFloydSteinberg Dither = "floyd_steinberg"
+	DitherAtkinson       Dither = "atkinson"
+)
+
+// ParseDither returns the method by name; empty selects Floyd-Steinberg,
+// which keeps the gradients of photos and logos
+func ParseDither(name string) (Dither, error) {
+	switch Dither(strings.ToLower(name)) {
+	case "", DitherFloydSteinberg, "floyd-steinberg", "floyd":
+		return DitherFloydSteinberg, nil
+	case DitherThreshold:
+		return DitherThreshold, nil
+	case DitherAtkinson:
+		return DitherAtkinson, nil
+	default:
+		return "", fmt.Errorf("unknown dithering method %q (use threshold, floyd_steinberg or atkinson)", name)
+	}
+}
+
+// diffusion is one neighbour receiving part of the quantization error
+type diffusion struct {
+	dx, dy int
+	weight int
+}
+
+// kernel is an error diffusion matrix; weights are divided by divisor
+type kernel struct {
+	divisor int
+	matrix  []diffusion
+}
+
+var (
+	floydSteinberg = kernel{16, []diffusion{{1, 0, 7}, {-1, 1, 3}, {0, 1, 5}, {1, 1, 1}}}
+
+	// Atkinson spreads only 6/8 of the error, keeping contrast on small logos
+	atkinson = kernel{8, []diffusion{{1, 0, 1}, {2, 0, 1}, {-1, 1, 1}, {0, 1, 1}, {1, 1, 1}, {0, 2, 1}}}
+)
+
+// FromImageDither scales the image to at most maxWidth dots and converts it
+// to monochrome with the dithering method
+func FromImageDither(img image.Image, maxWidth int, method Dither) *Bitmap {
+	gray := Scale(img, maxWidth)
+	switch method {
+	case DitherFloydSteinberg:
+		return diffuse(gray, floydSteinberg)
+	case DitherAtkinson:
+		return diffuse(gray, atkinson)
+	default:
+		return threshold(gray)
+	}
+}
+
+func threshold(gray *image.Gray) *Bitmap {
+	bounds := gray.Bounds()
+	bm := NewBitmap(bounds.Dx(), bounds.Dy())
+	for y := 0; y < bm.Height; y++ {
+		for x := 0; x < bm.Width; x++ {
+			bm.Pix[y*bm.Width+x] = gray.GrayAt(bounds.Min.X+x, bounds.Min.Y+y).Y < 128
+		}
+	}
+	return bm
+}
+
+// diffuse quantizes each pixel and spreads the error over the neighbours
+func diffuse(gray *image.Gray, k kernel) *Bitmap {
+	bounds := gray.Bounds()
+	bm := NewBitmap(bounds.Dx(), bounds.Dy())
+
+	levels := make([]int, bm.Width*bm.Height)
+	for y := 0; y < bm.Height; y++ {
+		for x := 0; x < bm.Width; x++ {
+			levels[y*bm.Width+x] = int(gray.GrayAt(bounds.Min.X+x, bounds.Min.Y+y).Y)
+		}
+	}
+
+	for y := 0; y < bm.Height; y++ {
+		for x := 0; x < bm.Width; x++ {
+			old := levels[y*bm.Width+x]
+			value := 255
+			if old < 128 {
+				value = 0
+				bm.Pix[y*bm.Width+x] = true
+			}
+			err := old - value
+			for _, d := range k.matrix {
+				nx, ny := x+d.dx, y+d.dy
+				if nx < 0 || nx >= bm.Width || ny >= bm.Height {
+					continue
+				}
+				levels[ny*bm.Width+nx] += err * d.weight / k.divisor
+			}
+		}
+	}
+	return bm
+}
@@ -0,0 +1,111 @@
+package raster
+
+import (
+	"image"
+	"image/color"
+)
+
+// Bitmap is a monochrome image where true means a printed (black) dot
+type Bitmap struct {
+	Width  int
+	Height int
+	Pix    []bool
+}
+
+// NewBitmap allocates a blank bitmap
+func NewBitmap(width, height int) *Bitmap {
+	return &Bitmap{Width: width, Height: height, Pix: make([]bool, width*height)}
+}
+
+// At reports whether the dot at x, y is black
+func (b *Bitmap) At(x, y int) bool {
+	if x < 0 || y < 0 || x >= b.Width || y >= b.Height {
+		return false
+	}
+	return b.Pix[y*b.Width+x]
+}
+
+// Set paints the dot at x, y
+func (b *Bitmap) Set(x, y int, black bool) {
+	if x < 0 || y < 0 || x >= b.Width || y >= b.Height {
+		return
+	}
+	b.Pix[y*b.Width+x] = black
+}
+
+// RowBytes returns the number of bytes per row when packed 8 dots per byte
+func (b *Bitmap) RowBytes() int {
+	return (b.Width + 7) / 8
+}
+
+// Packed returns the bitmap packed row by row, 8 dots per byte, MSB first
+func (b *Bitmap) Packed() []byte {
+	rowBytes := b.RowBytes()
+	out := make([]byte, rowBytes*b.Height)
+	for y := 0; y < b.Height; y++ {
+		for x := 0; x < b.Width; x++ {
+			if b.Pix[y*b.Width+x] {
+				out[y*rowBytes+x/8] |= 0x80 >> uint(x%8)
+			}
+		}
+	}
+	return out
+}
+
+// FromImage scales the image to at most maxWidth dots and converts it to
+// monochrome using a fixed threshold
+func FromImage(img image.Image, maxWidth int) *Bitmap {
+	return FromImageDither(img, maxWidth, DitherThreshold)
+}
+
+// Scale converts the image to grayscale and shrinks it to maxWidth dots
+// (keeping the aspect ratio), compositing transparent pixels over white
+func Scale(img image.Image, maxWidth int) *image.Gray {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	dstW, dstH := srcW, srcH
+	if maxWidth > 0 && srcW > maxWidth {
+		dstW = maxWidth
+		dstH = srcH * maxWidth / srcW
+		if dstH < 1 {
+			dstH = 1
+		}
+	}
+
+	out := image.NewGray(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		for x := 0; x < dstW; x++ {
+			// Average of the source area covered by the destination pixel
+			x0, x1 := x*srcW/dstW, (x+1)*srcW/dstW
+			y0, y1 := y*srcH/dstH, (y+1)*srcH/dstH
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+			if y1 == y0 {
+				y1 = y0 + 1
+			}
+			var sum, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					sum += luminance(img.At(bounds.Min.X+sx, bounds.Min.Y+sy))
+					n++
+				}
+			}
+			out.SetGray(x, y, color.Gray{Y: uint8(sum / n)})
+		}
+	}
+	return out
+}
+
+// luminance returns the gray level (0-255) of a color composited over white
+func luminance(c color.Color) uint32 {
+	r, g, b, a := c.RGBA()
+	// Transparent pixels become white
+	white := 0xffff - a
+	r, g, b = r+white, g+white, b+white
+	y := (19595*r + 38470*g + 7471*b + 1<<15) >> 24
+	if y > 255 {
+		y = 255
+	}
+	return y
+}
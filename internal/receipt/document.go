@@ -0,0 +1,147 @@
+package receipt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/willjrcom/gfood-printer/internal/raster"
+)
+
+// ContentType identifies a receipt document in messages and backend responses
+const ContentType = "application/vnd.gfood.receipt+json"
+
+// Element types
+const (
+	TypeText      = "text"
+	TypeSeparator = "separator"
+	TypeColumns   = "columns"
+	TypeTable     = "table"
+	TypeBarcode   = "barcode"
+	TypeQRCode    = "qrcode"
+	TypeImage     = "image"
+	TypeFeed      = "feed"
+	TypeCut       = "cut"
+	TypeDrawer    = "drawer"
+)
+
+// Document is a printer independent description of a ticket
+type Document struct {
+	Elements []Element `json:"elements"`
+}
+
+// Element is one block of the document. Which fields apply depends on Type.
+type Element struct {
+	Type string `json:"type"`
+
+	// text
+	Text      string `json:"text,omitempty"`
+	Align     string `json:"align,omitempty"` // left, center, right
+	Bold      bool   `json:"bold,omitempty"`
+	Underline bool   `json:"underline,omitempty"`
+	Size      string `json:"size,omitempty"` // normal, double, double_width, double_height
+	Font      string `json:"font,omitempty"` // a (default) or b
+
+	// separator
+	Char string `json:"char,omitempty"`
+
+	// columns and table
+	Columns []Column   `json:"columns,omitempty"`
+	Rows    [][]string `json:"rows,omitempty"`
+
+	// barcode, qrcode and image
+	Data            string `json:"data,omitempty"`
+	Symbology       string `json:"symbology,omitempty"` // code128, ean13, itf
+	Height          int    `json:"height,omitempty"`
+	Width           int    `json:"width,omitempty"`
+	HRI             bool   `json:"hri,omitempty"`
+	ModuleSize      int    `json:"module_size,omitempty"`
+	ErrorCorrection string `json:"error_correction,omitempty"` // L, M, Q, H
+	Dither          string `json:"dither,omitempty"`           // threshold, floyd_steinberg, atkinson
+
+	// feed
+	Lines int `json:"lines,omitempty"`
+
+	// cut
+	Partial bool `json:"partial,omitempty"`
+
+	// drawer
+	Pin   *int `json:"pin,omitempty"` // 0 = pin 2, 1 = pin 5; nil uses the printer default
+	OnMs  int  `json:"on_ms,omitempty"`
+	OffMs int  `json:"off_ms,omitempty"`
+}
+
+// Column describes a column of a columns or table element. Width is a fixed
+// number of characters; columns without width share the remaining space.
+type Column struct {
+	Text  string `json:"text,omitempty"`
+	Width int    `json:"width,omitempty"`
+	Align string `json:"align,omitempty"`
+	Bold  bool   `json:"bold,omitempty"`
+}
+
+// IsDocument reports whether the content is a receipt document, either by
+// its content type or by being a JSON object with an "elements" list
+func IsDocument(contentType string, data []byte) bool {
+	contentType = strings.ToLower(contentType)
+	if contentType == ContentType {
+		return true
+	}
+	if contentType != "" && contentType != "application/json" {
+		return false
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return false
+	}
+	var probe struct {
+		Elements json.RawMessage `json:"elements"`
+	}
+	return json.Unmarshal(trimmed, &probe) == nil && len(probe.Elements) > 0
+}
+
+// Parse decodes and validates a receipt document
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid receipt document: %v", err)
+	}
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// Validate checks that every element has a known type and its required fields
+func (d *Document) Validate() error {
+	if len(d.Elements) == 0 {
+		return fmt.Errorf("receipt document has no elements")
+	}
+	for i, e := range d.Elements {
+		switch e.Type {
+		case TypeText, TypeSeparator, TypeFeed, TypeCut, TypeDrawer:
+		case TypeColumns:
+			if len(e.Columns) == 0 {
+				return fmt.Errorf("element %d: columns without columns", i)
+			}
+		case TypeTable:
+			if len(e.Columns) == 0 {
+				return fmt.Errorf("element %d: table without columns", i)
+			}
+		case TypeBarcode, TypeQRCode, TypeImage:
+			if e.Data == "" {
+				return fmt.Errorf("element %d: %s without data", i, e.Type)
+			}
+			if e.Type == TypeImage && e.Dither != "" {
+				if _, err := raster.ParseDither(e.Dither); err != nil {
+					return fmt.Errorf("element %d: %v", i, err)
+				}
+			}
+		default:
+			return fmt.Errorf("element %d: unknown type %q", i, e.Type)
+		}
+	}
+	return nil
+}
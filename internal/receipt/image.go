@@ -0,0 +1,111 @@
+package receipt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"strings"
+	"sync"
+
+	"github.com/willjrcom/gfood-printer/internal/escpos"
+	"github.com/willjrcom/gfood-printer/internal/raster"
+)
+
+// maxCachedImages limits the converted images kept in memory
+const maxCachedImages = 32
+
+// imageCache keeps converted images by hash, so a logo printed on every
+// receipt is decoded, scaled and dithered only once
+var imageCache = &bitmapCache{entries: map[string]*raster.Bitmap{}}
+
+type bitmapCache struct {
+	mu      sync.Mutex
+	entries map[string]*raster.Bitmap
+	order   []string // oldest first
+}
+
+func (c *bitmapCache) get(key string) (*raster.Bitmap, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	bm, ok := c.entries[key]
+	return bm, ok
+}
+
+func (c *bitmapCache) put(key string, bm *raster.Bitmap) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; ok {
+		return
+	}
+	if len(c.order) >= maxCachedImages {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.entries[key] = bm
+	c.order = append(c.order, key)
+}
+
+// IsImage reports whether the content type is an image printed as a bitmap
+func IsImage(contentType string) bool {
+	mediaType := strings.SplitN(contentType, ";", 2)[0]
+	switch strings.ToLower(strings.TrimSpace(mediaType)) {
+	case "image/png", "image/jpeg", "image/jpg":
+		return true
+	}
+	return false
+}
+
+// RenderImage converts a PNG or JPEG into an ESC/POS command stream, centered
+// and scaled to the printer width
+func RenderImage(data []byte, opts Options) ([]byte, error) {
+	r := &renderer{opts: opts.withDefaults()}
+	r.buf.Init()
+	if err := r.drawImage(data, 0, "", ""); err != nil {
+		return nil, err
+	}
+	return r.buf.Bytes(), nil
+}
+
+func (r *renderer) image(e Element) error {
+	data, err := base64.StdEncoding.DecodeString(e.Data)
+	if err != nil {
+		return fmt.Errorf("invalid base64 image: %v", err)
+	}
+	return r.drawImage(data, e.Width, e.Dither, e.Align)
+}
+
+// drawImage prints the image at most width dots wide (the paper width when zero)
+func (r *renderer) drawImage(data []byte, width int, dither, align string) error {
+	method := r.opts.Dither
+	if dither != "" || method == "" {
+		var err error
+		if method, err = raster.ParseDither(dither); err != nil {
+			return err
+		}
+	}
+	if width <= 0 || width > r.opts.DotWidth {
+		width = r.opts.DotWidth
+	}
+
+	sum := sha256.Sum256(data)
+	key := fmt.Sprintf("%s:%d:%s", hex.EncodeToString(sum[:]), width, method)
+	bm, ok := imageCache.get(key)
+	if !ok {
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("invalid image: %v", err)
+		}
+		bm = raster.FromImageDither(img, width, method)
+		imageCache.put(key, bm)
+	}
+
+	r.buf.Align(alignOrCenter(align))
+	r.buf.Image(bm, r.opts.ImageMode)
+	r.buf.Align(escpos.AlignLeft)
+	return nil
+}
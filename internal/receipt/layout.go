@@ -0,0 +1,131 @@
+package receipt
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// textWidth counts characters, not bytes, since each character takes one
+// column once converted to the printer code page
+func textWidth(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
+// Truncate cuts the text to at most width characters
+func Truncate(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if textWidth(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:width])
+}
+
+// Pad aligns the text inside a field of width characters, truncating it
+// when it does not fit
+func Pad(s string, width int, align string) string {
+	s = Truncate(s, width)
+	gap := width - textWidth(s)
+	switch align {
+	case "right":
+		return strings.Repeat(" ", gap) + s
+	case "center":
+		left := gap / 2
+		return strings.Repeat(" ", left) + s + strings.Repeat(" ", gap-left)
+	default:
+		return s + strings.Repeat(" ", gap)
+	}
+}
+
+// Wrap breaks the text into lines of at most width characters, preferring
+// word boundaries and keeping explicit line breaks
+func Wrap(s string, width int) []string {
+	if width <= 0 {
+		return []string{s}
+	}
+
+	var lines []string
+	for _, paragraph := range strings.Split(s, "\n") {
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+
+		line := ""
+		for _, word := range words {
+			// Words longer than the line are split
+			for textWidth(word) > width {
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				runes := []rune(word)
+				lines = append(lines, string(runes[:width]))
+				word = string(runes[width:])
+			}
+			switch {
+			case word == "":
+			case line == "":
+				line = word
+			case textWidth(line)+1+textWidth(word) <= width:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// columnWidths resolves the width of each column: fixed widths are kept and
+// the remaining space is shared by the columns without width
+func columnWidths(cols []Column, width int) []int {
+	widths := make([]int, len(cols))
+	fixed, flexible := 0, 0
+	for i, c := range cols {
+		if c.Width > 0 {
+			widths[i] = c.Width
+			fixed += c.Width
+		} else {
+			flexible++
+		}
+	}
+	// One space between columns
+	remaining := width - fixed - (len(cols) - 1)
+	if flexible > 0 {
+		for i, c := range cols {
+			if c.Width > 0 {
+				continue
+			}
+			share := remaining / flexible
+			if share < 1 {
+				share = 1
+			}
+			widths[i] = share
+			remaining -= share
+			flexible--
+		}
+	}
+	return widths
+}
+
+// FormatRow lays out the texts side by side in the given columns
+func FormatRow(cols []Column, texts []string, width int) string {
+	widths := columnWidths(cols, width)
+	parts := make([]string, len(cols))
+	for i, c := range cols {
+		text := ""
+		if i < len(texts) {
+			text = texts[i]
+		}
+		parts[i] = Pad(text, widths[i], c.Align)
+	}
+	return strings.TrimRight(Truncate(strings.Join(parts, " "), width), " ")
+}
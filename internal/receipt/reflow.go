@@ -0,0 +1,77 @@
+package receipt
+
+import (
+	"regexp"
+	"strings"
+)
+
+// moneySuffix matches a trailing BRL amount
+var moneySuffix = regexp.MustCompile(`(?:-?R\$\s*)?-?\d[\d.]*,\d{2}$`)
+
+// Reflow fits plain text to width characters per line. Lines ending in a
+// price (or with a column separated by two or more spaces) keep the value
+// right aligned and truncate the item name; other long lines are word
+// wrapped. Lines with control characters are left untouched.
+func Reflow(text string, width int) string {
+	if width <= 0 {
+		return text
+	}
+
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \r")
+		if textWidth(line) <= width || hasControl(line) {
+			out = append(out, line)
+			continue
+		}
+
+		if left, right, ok := splitPriced(line); ok && textWidth(right) < width-4 {
+			out = append(out, justify(left, right, width))
+			continue
+		}
+
+		indent := line[:len(line)-len(strings.TrimLeft(line, " "))]
+		if len(indent) >= width/2 {
+			indent = ""
+		}
+		for i, wrapped := range Wrap(strings.TrimLeft(line, " "), width-len(indent)) {
+			if i == 0 || indent != "" {
+				wrapped = indent + wrapped
+			}
+			out = append(out, wrapped)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// splitPriced separates the item text from its right column
+func splitPriced(line string) (string, string, bool) {
+	if loc := moneySuffix.FindStringIndex(line); loc != nil && loc[0] > 0 {
+		left := strings.TrimRight(line[:loc[0]], " .")
+		if left != "" {
+			return left, line[loc[0]:], true
+		}
+	}
+	// Otherwise the last gap of two or more spaces separates the columns
+	gap := strings.LastIndex(line, "  ")
+	if gap < 0 {
+		return "", "", false
+	}
+	left := strings.TrimRight(line[:gap], " .")
+	right := strings.TrimSpace(line[gap:])
+	if left == "" || right == "" || strings.Contains(right, "  ") {
+		return "", "", false
+	}
+	return left, right, true
+}
+
+// hasControl reports whether the line carries printer commands
+func hasControl(line string) bool {
+	for i := 0; i < len(line); i++ {
+		if line[i] < 0x20 && line[i] != '\t' {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,418 @@
+package receipt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/willjrcom/gfood-printer/internal/barcode"
+	"github.com/willjrcom/gfood-printer/internal/escpos"
+	"github.com/willjrcom/gfood-printer/internal/raster"
+)
+
+// Options describes the target printer
+type Options struct {
+	Columns      int // characters per line in font A at normal size
+	ColumnsFontB int // characters per line in font B; derived from Columns when zero
+	DotWidth     int // printable width in dots
+
+	// Native symbol commands; when false the symbols are drawn as raster images
+	NativeQR      bool
+	NativeBarcode bool
+
+	// ImageMode selects GS v 0 (escpos.ImageRaster, default) or ESC * (escpos.ImageColumn)
+	ImageMode string
+	// Dither is the default conversion of images to black and white
+	Dither raster.Dither
+
+	// Cut is the cutter capability (escpos.CutPartial, CutFull or CutNone)
+	Cut string
+
+	// CodePage converts the text; nil writes UTF-8 unchanged
+	CodePage *escpos.CodePage
+
+	// Cash drawer pulse used by drawer elements that do not set their own
+	// (pin 0 = pin 2, 1 = pin 5; times in milliseconds, zero = 100/500)
+	DrawerPin   int
+	DrawerOnMs  int
+	DrawerOffMs int
+}
+
+// Default cash drawer pulse
+const (
+	DefaultDrawerOnMs  = 100
+	DefaultDrawerOffMs = 500
+)
+
+// DefaultOptions matches an 80mm printer
+var DefaultOptions = Options{Columns: 48, ColumnsFontB: 64, DotWidth: 576, NativeQR: true, NativeBarcode: true}
+
+// OptionsFromProfile returns the render options for the printer geometry
+func OptionsFromProfile(p escpos.Profile) Options {
+	return Options{
+		Columns:       p.ColumnsFontA,
+		ColumnsFontB:  p.ColumnsFontB,
+		DotWidth:      p.DotWidth,
+		NativeQR:      p.NativeQR,
+		NativeBarcode: p.NativeBarcode,
+		ImageMode:     p.ImageMode,
+		Cut:           p.Cut,
+	}
+}
+
+func (o Options) withDefaults() Options {
+	if o.Columns <= 0 {
+		o.Columns = DefaultOptions.Columns
+	}
+	if o.DotWidth <= 0 {
+		o.DotWidth = DefaultOptions.DotWidth
+	}
+	return o
+}
+
+// fontColumns returns the characters per line for the font at normal size
+func (o Options) fontColumns(font string) int {
+	if font == "b" {
+		if o.ColumnsFontB > 0 {
+			return o.ColumnsFontB
+		}
+		// Font B is 9 dots wide instead of 12
+		return o.Columns * 4 / 3
+	}
+	return o.Columns
+}
+
+// Render converts the document into an ESC/POS command stream
+func Render(doc *Document, opts Options) ([]byte, error) {
+	r := &renderer{opts: opts.withDefaults()}
+	r.buf.Init()
+	if r.opts.CodePage != nil {
+		r.buf.CodePage(r.opts.CodePage)
+	}
+	for i, e := range doc.Elements {
+		if err := r.element(e); err != nil {
+			return nil, fmt.Errorf("element %d (%s): %v", i, e.Type, err)
+		}
+	}
+	return r.buf.Bytes(), nil
+}
+
+type renderer struct {
+	buf  escpos.Buffer
+	opts Options
+}
+
+// line writes a line of text converted to the printer code page
+func (r *renderer) line(s string) {
+	if r.opts.CodePage != nil {
+		r.buf.Write(r.opts.CodePage.Encode(s))
+		r.buf.WriteByte(escpos.LF)
+		return
+	}
+	r.buf.Line(s)
+}
+
+func (r *renderer) element(e Element) error {
+	switch e.Type {
+	case TypeText:
+		r.text(e)
+	case TypeSeparator:
+		char := e.Char
+		if char == "" {
+			char = "-"
+		}
+		r.buf.Align(escpos.AlignLeft)
+		r.line(Truncate(strings.Repeat(char, r.opts.Columns), r.opts.Columns))
+	case TypeColumns:
+		r.columns(e)
+	case TypeTable:
+		r.table(e)
+	case TypeBarcode:
+		return r.barcode(e)
+	case TypeQRCode:
+		return r.qrcode(e)
+	case TypeImage:
+		return r.image(e)
+	case TypeFeed:
+		lines := e.Lines
+		if lines <= 0 {
+			lines = 1
+		}
+		r.buf.Feed(lines)
+	case TypeCut:
+		r.cut(e.Partial)
+	case TypeDrawer:
+		pin, onMs, offMs := r.opts.DrawerPin, r.opts.DrawerOnMs, r.opts.DrawerOffMs
+		if e.Pin != nil {
+			pin = *e.Pin
+		}
+		if e.OnMs > 0 {
+			onMs = e.OnMs
+		}
+		if e.OffMs > 0 {
+			offMs = e.OffMs
+		}
+		if onMs <= 0 {
+			onMs = DefaultDrawerOnMs
+		}
+		if offMs <= 0 {
+			offMs = DefaultDrawerOffMs
+		}
+		r.buf.DrawerKick(pin, onMs, offMs)
+	default:
+		return fmt.Errorf("unknown element type")
+	}
+	return nil
+}
+
+// cut honours the cutter of the printer; without one the paper is fed up to
+// the tear bar
+func (r *renderer) cut(partial bool) {
+	switch r.opts.Cut {
+	case escpos.CutNone:
+		r.buf.Feed(4)
+	case escpos.CutFull:
+		r.buf.Cut(false)
+	default:
+		r.buf.Cut(partial)
+	}
+}
+
+// sizeMultipliers converts the size name into width and height multipliers
+func sizeMultipliers(size string) (int, int) {
+	switch size {
+	case "double":
+		return 2, 2
+	case "double_width":
+		return 2, 1
+	case "double_height":
+		return 1, 2
+	default:
+		return 1, 1
+	}
+}
+
+func (r *renderer) text(e Element) {
+	w, h := sizeMultipliers(e.Size)
+	width := r.opts.fontColumns(e.Font) / w
+
+	r.buf.Align(escpos.ParseAlignment(e.Align))
+	if e.Font == "b" {
+		r.buf.Font(1)
+	}
+	if e.Bold {
+		r.buf.Bold(true)
+	}
+	if e.Underline {
+		r.buf.Underline(true)
+	}
+	if w != 1 || h != 1 {
+		r.buf.Size(w, h)
+	}
+
+	for _, line := range Wrap(e.Text, width) {
+		r.line(line)
+	}
+
+	// Restore the defaults so the next element starts clean
+	if w != 1 || h != 1 {
+		r.buf.Size(1, 1)
+	}
+	if e.Underline {
+		r.buf.Underline(false)
+	}
+	if e.Bold {
+		r.buf.Bold(false)
+	}
+	if e.Font == "b" {
+		r.buf.Font(0)
+	}
+}
+
+func (r *renderer) columns(e Element) {
+	texts := make([]string, len(e.Columns))
+	for i, c := range e.Columns {
+		texts[i] = c.Text
+	}
+	r.buf.Align(escpos.AlignLeft)
+	if e.Bold {
+		r.buf.Bold(true)
+	}
+	r.line(FormatRow(e.Columns, texts, r.opts.Columns))
+	if e.Bold {
+		r.buf.Bold(false)
+	}
+}
+
+func (r *renderer) table(e Element) {
+	r.buf.Align(escpos.AlignLeft)
+
+	hasHeader := false
+	headers := make([]string, len(e.Columns))
+	for i, c := range e.Columns {
+		headers[i] = c.Text
+		hasHeader = hasHeader || c.Text != ""
+	}
+	if hasHeader {
+		r.buf.Bold(true)
+		r.line(FormatRow(e.Columns, headers, r.opts.Columns))
+		r.buf.Bold(false)
+		r.line(strings.Repeat("-", r.opts.Columns))
+	}
+
+	for _, row := range e.Rows {
+		r.line(FormatRow(e.Columns, row, r.opts.Columns))
+	}
+}
+
+func (r *renderer) barcode(e Element) error {
+	symbology, err := barcodeSymbology(e.Symbology, e.Data)
+	if err != nil {
+		return err
+	}
+	height := e.Height
+	if height <= 0 {
+		height = 80
+	}
+	width := e.Width
+	if width <= 0 {
+		width = 3
+	}
+
+	r.buf.Align(alignOrCenter(e.Align))
+	if r.opts.NativeBarcode {
+		if err := r.buf.Barcode(symbology, e.Data, height, width, e.HRI); err != nil {
+			return err
+		}
+	} else if err := r.rasterBarcode(symbology, e.Data, height, width, e.HRI); err != nil {
+		return err
+	}
+	r.buf.Align(escpos.AlignLeft)
+	return nil
+}
+
+// rasterBarcode draws the barcode as an image for printers without GS k
+func (r *renderer) rasterBarcode(symbology byte, data string, height, width int, hri bool) error {
+	var code *barcode.Linear
+	var err error
+	switch symbology {
+	case escpos.BarcodeEAN13:
+		code, err = barcode.EAN13(data)
+	case escpos.BarcodeITF:
+		code, err = barcode.ITF(data)
+	default:
+		code, err = barcode.Code128(data)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Narrow the modules until the bars fit the paper
+	for width > 1 && (len(code.Modules)+2*barcode.LinearQuietZone)*width > r.opts.DotWidth {
+		width--
+	}
+	bm := code.Bitmap(width, height)
+	if bm.Width > r.opts.DotWidth {
+		return fmt.Errorf("barcode too wide for the paper (%d dots)", bm.Width)
+	}
+	r.buf.Image(bm, r.opts.ImageMode)
+	if hri {
+		r.line(code.Text)
+	}
+	return nil
+}
+
+// barcodeSymbology validates the data for the symbology
+func barcodeSymbology(name, data string) (byte, error) {
+	switch strings.ToLower(name) {
+	case "", "code128":
+		return escpos.BarcodeCode128, nil
+	case "ean13":
+		if !isDigits(data) || (len(data) != 12 && len(data) != 13) {
+			return 0, fmt.Errorf("ean13 requires 12 or 13 digits")
+		}
+		return escpos.BarcodeEAN13, nil
+	case "itf":
+		if !isDigits(data) || len(data)%2 != 0 {
+			return 0, fmt.Errorf("itf requires an even number of digits")
+		}
+		return escpos.BarcodeITF, nil
+	default:
+		return 0, fmt.Errorf("unsupported symbology %q", name)
+	}
+}
+
+func (r *renderer) qrcode(e Element) error {
+	level, err := qrLevel(e.ErrorCorrection)
+	if err != nil {
+		return err
+	}
+	size := e.ModuleSize
+	if size <= 0 {
+		size = 6
+	}
+
+	r.buf.Align(alignOrCenter(e.Align))
+	if r.opts.NativeQR {
+		if err := r.buf.QRCode(e.Data, size, level); err != nil {
+			return err
+		}
+	} else if err := r.rasterQRCode(e.Data, size, level); err != nil {
+		return err
+	}
+	r.buf.Align(escpos.AlignLeft)
+	return nil
+}
+
+// rasterQRCode draws the QR code as an image for printers without GS ( k
+func (r *renderer) rasterQRCode(data string, moduleSize int, level byte) error {
+	code, err := barcode.EncodeQR([]byte(data), int(level-escpos.QRLevelL))
+	if err != nil {
+		return err
+	}
+
+	// Shrink the modules until the symbol fits the paper
+	for moduleSize > 1 && (code.Size+2*barcode.QRQuietZone)*moduleSize > r.opts.DotWidth {
+		moduleSize--
+	}
+	bm := code.Bitmap(moduleSize)
+	if bm.Width > r.opts.DotWidth {
+		return fmt.Errorf("QR code too large for the paper (%d dots)", bm.Width)
+	}
+	r.buf.Image(bm, r.opts.ImageMode)
+	return nil
+}
+
+func qrLevel(name string) (byte, error) {
+	switch strings.ToUpper(name) {
+	case "L":
+		return escpos.QRLevelL, nil
+	case "", "M":
+		return escpos.QRLevelM, nil
+	case "Q":
+		return escpos.QRLevelQ, nil
+	case "H":
+		return escpos.QRLevelH, nil
+	default:
+		return 0, fmt.Errorf("invalid error correction level %q", name)
+	}
+}
+
+// alignOrCenter centers graphics unless another alignment was requested
+func alignOrCenter(align string) escpos.Alignment {
+	if align == "" {
+		return escpos.AlignCenter
+	}
+	return escpos.ParseAlignment(align)
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
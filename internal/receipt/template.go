@@ -0,0 +1,215 @@
+package receipt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/willjrcom/gfood-printer/internal/escpos"
+)
+
+// Template is a text/template with receipt helpers bound to a line width
+type Template struct {
+	tmpl *template.Template
+}
+
+// ParseTemplate compiles a ticket template. The helpers use width as the
+// number of characters per line.
+func ParseTemplate(name, text string, width int) (*Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=zero").Funcs(TemplateFuncs(width)).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{tmpl: tmpl}, nil
+}
+
+// Execute renders the template with the JSON data
+func (t *Template) Execute(data []byte) ([]byte, error) {
+	var value interface{}
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, fmt.Errorf("invalid template data: %v", err)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := t.tmpl.Execute(&out, value); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// TemplateFuncs returns the receipt helpers available to templates
+func TemplateFuncs(width int) template.FuncMap {
+	return template.FuncMap{
+		// Layout
+		"width":    func() int { return width },
+		"left":     func(w int, v interface{}) string { return Pad(toString(v), w, "left") },
+		"right":    func(w int, v interface{}) string { return Pad(toString(v), w, "right") },
+		"center":   func(v interface{}) string { return strings.TrimRight(Pad(toString(v), width, "center"), " ") },
+		"truncate": func(w int, v interface{}) string { return Truncate(toString(v), w) },
+		"line":     func(char string) string { return Truncate(strings.Repeat(char, width), width) },
+		"row":      func(l, r interface{}) string { return justify(toString(l), toString(r), width) },
+		"cols":     cols(width),
+		"wrap": func(v interface{}) string {
+			return strings.Join(Wrap(toString(v), width), "\n")
+		},
+		"wrapw": func(w int, v interface{}) string {
+			return strings.Join(Wrap(toString(v), w), "\n")
+		},
+		"indent": func(n int, v interface{}) string {
+			pad := strings.Repeat(" ", n)
+			return pad + strings.ReplaceAll(toString(v), "\n", "\n"+pad)
+		},
+
+		// Values
+		"brl":   func(v interface{}) string { return FormatBRL(toFloat(v)) },
+		"date":  formatDate,
+		"upper": func(v interface{}) string { return strings.ToUpper(toString(v)) },
+		"lower": func(v interface{}) string { return strings.ToLower(toString(v)) },
+		"add":   func(a, b interface{}) float64 { return toFloat(a) + toFloat(b) },
+		"mul":   func(a, b interface{}) float64 { return toFloat(a) * toFloat(b) },
+
+		// Printer commands
+		"bold": func(v interface{}) string {
+			return command(func(b *escpos.Buffer) { b.Bold(true) }) + toString(v) + command(func(b *escpos.Buffer) { b.Bold(false) })
+		},
+		"double": func(v interface{}) string {
+			return command(func(b *escpos.Buffer) { b.Size(2, 2) }) + toString(v) + command(func(b *escpos.Buffer) { b.Size(1, 1) })
+		},
+		"feed": func(n int) string { return strings.Repeat("\n", n) },
+		"cut":  func() string { return command(func(b *escpos.Buffer) { b.Cut(false) }) },
+	}
+}
+
+// command returns the bytes written by the ESC/POS builder as a string
+func command(write func(b *escpos.Buffer)) string {
+	var b escpos.Buffer
+	write(&b)
+	return b.String()
+}
+
+// justify places left at the start and right at the end of the line,
+// truncating left when both do not fit
+func justify(left, right string, width int) string {
+	room := width - textWidth(right) - 1
+	if room < 0 {
+		return Truncate(right, width)
+	}
+	left = Truncate(left, room)
+	return left + strings.Repeat(" ", width-textWidth(left)-textWidth(right)) + right
+}
+
+// cols lays out pairs of width and value in a single line, e.g.
+// {{cols 4 .qty 0 .name 10 (brl .total)}}. A zero width takes the remaining
+// space; a negative width right-aligns the value.
+func cols(width int) func(args ...interface{}) (string, error) {
+	return func(args ...interface{}) (string, error) {
+		if len(args)%2 != 0 {
+			return "", fmt.Errorf("cols expects width and value pairs")
+		}
+		columns := make([]Column, 0, len(args)/2)
+		texts := make([]string, 0, len(args)/2)
+		for i := 0; i < len(args); i += 2 {
+			w := int(toFloat(args[i]))
+			align := "left"
+			if w < 0 {
+				w, align = -w, "right"
+			}
+			columns = append(columns, Column{Width: w, Align: align})
+			texts = append(texts, toString(args[i+1]))
+		}
+		return FormatRow(columns, texts, width), nil
+	}
+}
+
+// FormatBRL formats the value as Brazilian real, e.g. R$ 1.234,56
+func FormatBRL(v float64) string {
+	sign := ""
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	cents := int64(math.Round(v * 100))
+	integer := strconv.FormatInt(cents/100, 10)
+
+	// Thousands separator
+	var grouped strings.Builder
+	for i, c := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			grouped.WriteByte('.')
+		}
+		grouped.WriteRune(c)
+	}
+	return fmt.Sprintf("%sR$ %s,%02d", sign, grouped.String(), cents%100)
+}
+
+// formatDate formats a time or a date string using the Go layout, e.g.
+// {{date "02/01/2006 15:04" .created_at}}
+func formatDate(layout string, v interface{}) string {
+	var t time.Time
+	switch value := v.(type) {
+	case time.Time:
+		t = value
+	case string:
+		parsed, ok := parseTime(value)
+		if !ok {
+			return value
+		}
+		t = parsed
+	case float64:
+		t = time.Unix(int64(value), 0)
+	default:
+		return toString(v)
+	}
+	return t.Local().Format(layout)
+}
+
+func parseTime(s string) (time.Time, bool) {
+	layouts := []string{time.RFC3339Nano, time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func toString(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func toFloat(v interface{}) float64 {
+	switch value := v.(type) {
+	case float64:
+		return value
+	case float32:
+		return float64(value)
+	case int:
+		return float64(value)
+	case int64:
+		return float64(value)
+	case json.Number:
+		f, _ := value.Float64()
+		return f
+	case string:
+		f, _ := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+		return f
+	default:
+		return 0
+	}
+}
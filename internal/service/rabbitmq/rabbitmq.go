@@ -13,14 +13,28 @@ const (
 	SHIFT_EX      = "print.shift"
 	GROUP_ITEM_EX = "print.group.item"
 	ORDER_EX      = "print.order"
+	RESULT_EX     = "print.result"
 )
 
+// prefetchCount limits unacknowledged deliveries per consumer, so a paused
+// consumer does not pull the whole queue into memory
+const prefetchCount = 10
+
 // RabbitMQ structure to manage connection and channel
 type RabbitMQ struct {
 	conn    *amqp.Connection
 	channel *amqp.Channel
 	url     string
 	mu      sync.Mutex
+
+	// Publisher state lives on its own channel in confirm mode so that
+	// publishing never interferes with the consumer channel.
+	pubMu       sync.Mutex
+	pubConn     *amqp.Connection
+	pubChannel  *amqp.Channel
+	pubConfirms chan amqp.Confirmation
+	pubTag      uint64
+	pubDeclared map[string]bool
 }
 
 // NewInstance creates and returns a new instance of RabbitMQ with retries
@@ -42,6 +56,9 @@ func (r *RabbitMQ) connect() error {
 		r.conn, err = amqp.Dial(r.url)
 		if err == nil {
 			r.channel, err = r.conn.Channel()
+			if err == nil {
+				err = r.channel.Qos(prefetchCount, 0, false)
+			}
 			if err == nil {
 				log.Println("Successfully connected to RabbitMQ")
 				return nil
@@ -64,8 +81,49 @@ func (r *RabbitMQ) reconnectIfClosed() error {
 	return nil
 }
 
+// Binding describes an exchange, the queue consumed from it and its routing keys
+type Binding struct {
+	Exchange     string   // Base name, declared as <exchange>_exchange (or <exchange>_topic_exchange)
+	ExchangeType string   // "direct" (default) or "topic"
+	Queue        string   // Defaults to <exchange>_<first routing key>_queue
+	RoutingKeys  []string // Routing keys or topic patterns (e.g. "schema.kitchen.#")
+}
+
+// ExchangeName returns the declared name of the exchange. Topic exchanges get
+// their own name so they can coexist with the direct exchange of the same base.
+func (b Binding) ExchangeName() string {
+	if b.ExchangeType == "topic" {
+		return fmt.Sprintf("%s_topic_exchange", b.Exchange)
+	}
+	return fmt.Sprintf("%s_exchange", b.Exchange)
+}
+
+// QueueName returns the queue consumed for the binding
+func (b Binding) QueueName() string {
+	if b.Queue != "" {
+		return b.Queue
+	}
+	routingKey := ""
+	if len(b.RoutingKeys) > 0 {
+		routingKey = b.RoutingKeys[0]
+	}
+	return fmt.Sprintf("%s_%s_queue", b.Exchange, routingKey)
+}
+
+func (b Binding) exchangeType() string {
+	if b.ExchangeType == "" {
+		return "direct"
+	}
+	return b.ExchangeType
+}
+
 // EnsureExchangeQueueAndBind ensures the exchange, queue, and binding exist
 func (r *RabbitMQ) EnsureExchangeQueueAndBind(exchange, routingKey string) error {
+	return r.EnsureBinding(Binding{Exchange: exchange, RoutingKeys: []string{routingKey}})
+}
+
+// EnsureBinding ensures the exchange, queue, and every routing key binding exist
+func (r *RabbitMQ) EnsureBinding(b Binding) error {
 	if err := r.reconnectIfClosed(); err != nil {
 		return err
 	}
@@ -74,18 +132,18 @@ func (r *RabbitMQ) EnsureExchangeQueueAndBind(exchange, routingKey string) error
 	defer r.mu.Unlock()
 
 	// Names for Exchange and Queue
-	exchangeName := fmt.Sprintf("%s_exchange", exchange)
-	queueName := fmt.Sprintf("%s_%s_queue", exchange, routingKey)
+	exchangeName := b.ExchangeName()
+	queueName := b.QueueName()
 
-	// Declare the Exchange (direct type)
+	// Declare the Exchange
 	err := r.channel.ExchangeDeclare(
-		exchangeName, // Name of the exchange
-		"direct",     // Type of exchange
-		true,         // Durable
-		false,        // Auto-deleted
-		false,        // Internal
-		false,        // No-wait
-		nil,          // Arguments
+		exchangeName,     // Name of the exchange
+		b.exchangeType(), // Type of exchange
+		true,             // Durable
+		false,            // Auto-deleted
+		false,            // Internal
+		false,            // No-wait
+		nil,              // Arguments
 	)
 	if err != nil {
 		return fmt.Errorf("failed to declare exchange: %s", err)
@@ -104,25 +162,48 @@ func (r *RabbitMQ) EnsureExchangeQueueAndBind(exchange, routingKey string) error
 		return fmt.Errorf("failed to declare queue: %s", err)
 	}
 
-	// Bind the Queue to the Exchange with the Routing Key
-	err = r.channel.QueueBind(
-		queueName,    // Queue name
-		routingKey,   // Routing Key (topic)
-		exchangeName, // Exchange name
-		false,        // No-wait
-		nil,          // Arguments
-	)
-	if err != nil {
-		return fmt.Errorf("failed to bind queue to exchange: %s", err)
+	// Bind the Queue to the Exchange with each Routing Key
+	for _, routingKey := range b.RoutingKeys {
+		err = r.channel.QueueBind(
+			queueName,    // Queue name
+			routingKey,   // Routing Key (topic)
+			exchangeName, // Exchange name
+			false,        // No-wait
+			nil,          // Arguments
+		)
+		if err != nil {
+			return fmt.Errorf("failed to bind queue to exchange: %s", err)
+		}
 	}
 
 	return nil
 }
 
+// QueueDepth returns the messages ready for delivery and the consumers of a queue
+func (r *RabbitMQ) QueueDepth(queue string) (messages, consumers int, err error) {
+	if err := r.reconnectIfClosed(); err != nil {
+		return 0, 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	q, err := r.channel.QueueInspect(queue)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to inspect queue: %s", err)
+	}
+	return q.Messages, q.Consumers, nil
+}
+
 // ConsumeMessages starts consuming messages from the specified company's queue
 func (r *RabbitMQ) ConsumeMessages(exchange, routingKey string) (<-chan amqp.Delivery, error) {
+	return r.ConsumeBinding(Binding{Exchange: exchange, RoutingKeys: []string{routingKey}})
+}
+
+// ConsumeBinding ensures the binding exists and starts consuming its queue
+func (r *RabbitMQ) ConsumeBinding(b Binding) (<-chan amqp.Delivery, error) {
 	// Ensure the exchange, queue, and binding exist
-	err := r.EnsureExchangeQueueAndBind(exchange, routingKey)
+	err := r.EnsureBinding(b)
 	if err != nil {
 		return nil, fmt.Errorf("failed to ensure exchange, queue and binding: %s", err)
 	}
@@ -131,15 +212,14 @@ func (r *RabbitMQ) ConsumeMessages(exchange, routingKey string) (<-chan amqp.Del
 	defer r.mu.Unlock()
 
 	// Start consuming messages from the queue
-	queueName := fmt.Sprintf("%s_%s_queue", exchange, routingKey)
 	msgs, err := r.channel.Consume(
-		queueName, // Queue name
-		"",        // Consumer name
-		false,     // Auto-ack
-		false,     // Exclusive
-		false,     // No-local
-		false,     // No-wait
-		nil,       // Arguments
+		b.QueueName(), // Queue name
+		"",            // Consumer name
+		false,         // Auto-ack
+		false,         // Exclusive
+		false,         // No-local
+		false,         // No-wait
+		nil,           // Arguments
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to consume messages: %s", err)
@@ -148,8 +228,118 @@ func (r *RabbitMQ) ConsumeMessages(exchange, routingKey string) (<-chan amqp.Del
 	return msgs, nil
 }
 
+// openPublisher opens a dedicated channel in confirm mode for the current connection
+func (r *RabbitMQ) openPublisher() error {
+	r.mu.Lock()
+	conn := r.conn
+	r.mu.Unlock()
+
+	if r.pubChannel != nil && r.pubConn == conn {
+		return nil
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open publisher channel: %s", err)
+	}
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		return fmt.Errorf("failed to enable publisher confirms: %s", err)
+	}
+
+	r.pubConn = conn
+	r.pubChannel = ch
+	r.pubConfirms = ch.NotifyPublish(make(chan amqp.Confirmation, 16))
+	r.pubTag = 0
+	r.pubDeclared = map[string]bool{}
+	return nil
+}
+
+// resetPublisher drops the publisher channel so the next publish reopens it
+func (r *RabbitMQ) resetPublisher() {
+	if r.pubChannel != nil {
+		r.pubChannel.Close()
+	}
+	r.pubChannel = nil
+	r.pubConn = nil
+}
+
+// PublishWithConfirm publishes a persistent message to the exchange and waits
+// for the broker to confirm it
+func (r *RabbitMQ) PublishWithConfirm(exchange, routingKey string, msg amqp.Publishing, timeout time.Duration) error {
+	if err := r.reconnectIfClosed(); err != nil {
+		return err
+	}
+
+	r.pubMu.Lock()
+	defer r.pubMu.Unlock()
+
+	if err := r.openPublisher(); err != nil {
+		return err
+	}
+
+	exchangeName := fmt.Sprintf("%s_exchange", exchange)
+	if !r.pubDeclared[exchangeName] {
+		err := r.pubChannel.ExchangeDeclare(
+			exchangeName, // Name of the exchange
+			"direct",     // Type of exchange
+			true,         // Durable
+			false,        // Auto-deleted
+			false,        // Internal
+			false,        // No-wait
+			nil,          // Arguments
+		)
+		if err != nil {
+			r.resetPublisher()
+			return fmt.Errorf("failed to declare exchange: %s", err)
+		}
+		r.pubDeclared[exchangeName] = true
+	}
+
+	msg.DeliveryMode = amqp.Persistent
+	if err := r.pubChannel.Publish(exchangeName, routingKey, false, false, msg); err != nil {
+		r.resetPublisher()
+		return fmt.Errorf("failed to publish message: %s", err)
+	}
+	r.pubTag++
+	tag := r.pubTag
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case confirm, ok := <-r.pubConfirms:
+			if !ok {
+				r.resetPublisher()
+				return fmt.Errorf("publisher channel closed before confirmation")
+			}
+			// Late confirmations from publishes that timed out are skipped
+			if confirm.DeliveryTag < tag {
+				continue
+			}
+			if !confirm.Ack {
+				return fmt.Errorf("message was not acknowledged by the broker")
+			}
+			return nil
+		case <-timer.C:
+			// Late confirmations would pile up in the buffer and, once it is
+			// full, stall the whole connection. Start over on a fresh channel
+			// with its own confirm listener.
+			r.resetPublisher()
+			if err := r.openPublisher(); err != nil {
+				log.Printf("Failed to reopen publisher channel after confirm timeout: %s", err)
+			}
+			return fmt.Errorf("timed out waiting for publisher confirmation")
+		}
+	}
+}
+
 // Close closes the RabbitMQ connection and channel
 func (r *RabbitMQ) Close() {
+	r.pubMu.Lock()
+	r.resetPublisher()
+	r.pubMu.Unlock()
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
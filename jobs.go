@@ -0,0 +1,234 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	jobsDirName   = "jobs"
+	maxStoredJobs = 200
+)
+
+// Situação de um job
+const (
+	JobSent      = "sent"      // entregue ao sistema de impressão
+	JobCompleted = "completed" // impressão confirmada pela fila do CUPS
+	JobHeld      = "held"      // retido ou parado na fila do CUPS
+	JobFailed    = "failed"    // recusado, cancelado ou abortado
+)
+
+// Origem de um job
+const (
+	SourceWebSocket = "websocket"
+	SourceRabbitMQ  = "rabbitmq"
+	SourceHTTP      = "http"
+	SourceCLI       = "cli"
+)
+
+var (
+	jobIDRe = regexp.MustCompile(`^[0-9]{8}-[0-9]{6}-[0-9a-f]{8}$`)
+	jobsMu  sync.Mutex
+)
+
+// Job registra os bytes enviados a uma impressora, para consulta, prévia e
+// reimpressão. Os últimos maxStoredJobs ficam na pasta jobs/ ao lado do
+// executável: <id>.bin com o conteúdo e <id>.json com estes dados.
+type Job struct {
+	ID        string    `json:"id"`
+	Printer   string    `json:"printer"`             // impressora que recebeu o job
+	Requested string    `json:"requested,omitempty"` // impressora, estação ou grupo pedido
+	Source    string    `json:"source"`
+	MessageID string    `json:"message_id,omitempty"`
+	Exchange  string    `json:"exchange,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	Size      int       `json:"size"`
+	Copies    int       `json:"copies,omitempty"` // cópias repetidas no conteúdo
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Impressoras pedidas que falharam ou não foram encontradas antes desta
+	Failover []JobAttempt `json:"failover,omitempty"`
+
+	// Job no spooler do sistema: id no CUPS (ex.: EPSON-12) ou no Windows e,
+	// no CUPS, a situação acompanhada pelo lpstat
+	SystemJobID string     `json:"system_job_id,omitempty"`
+	SystemState string     `json:"system_state,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+func jobsDir() string {
+	return filepath.Join(appDir(), jobsDirName)
+}
+
+// newJobID gera um id ordenável pela data de criação
+func newJobID(t time.Time) string {
+	var suffix [4]byte
+	rand.Read(suffix[:])
+	return t.Format("20060102-150405") + "-" + hex.EncodeToString(suffix[:])
+}
+
+// printJob envia o conteúdo para a impressora e registra o job
+func printJob(job *Job, data []byte) error {
+	job.CreatedAt = time.Now()
+	job.ID = newJobID(job.CreatedAt)
+	job.Size = len(data)
+
+	err := sendToPrinter(job, data)
+	job.Status = JobSent
+	if err != nil {
+		job.Status = JobFailed
+		job.Error = err.Error()
+	}
+
+	if saveErr := saveJob(job, data); saveErr != nil {
+		log.Printf("Jobs: Erro ao registrar job %s: %v", job.ID, saveErr)
+	}
+	if err == nil {
+		trackCUPSJob(*job)
+	}
+	return err
+}
+
+// updateJob regrava os dados de um job já registrado; jobs removidos pela
+// limpeza não são recriados
+func updateJob(job *Job) error {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+
+	path := filepath.Join(jobsDir(), job.ID+".json")
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	meta, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, meta, 0644)
+}
+
+// saveJob grava o conteúdo e os dados do job e remove os mais antigos
+func saveJob(job *Job, data []byte) error {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+
+	dir := jobsDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if data != nil {
+		if err := os.WriteFile(filepath.Join(dir, job.ID+".bin"), data, 0644); err != nil {
+			return err
+		}
+	}
+	meta, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, job.ID+".json"), meta, 0644); err != nil {
+		return err
+	}
+	return pruneJobs(dir)
+}
+
+// pruneJobs mantém apenas os maxStoredJobs mais recentes
+func pruneJobs(dir string) error {
+	ids, err := jobIDs(dir)
+	if err != nil {
+		return err
+	}
+	for i := maxStoredJobs; i < len(ids); i++ {
+		os.Remove(filepath.Join(dir, ids[i]+".bin"))
+		os.Remove(filepath.Join(dir, ids[i]+".json"))
+	}
+	return nil
+}
+
+// jobIDs lista os ids gravados, do mais recente para o mais antigo
+func jobIDs(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, e := range entries {
+		id := strings.TrimSuffix(e.Name(), ".json")
+		if id != e.Name() && jobIDRe.MatchString(id) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	return ids, nil
+}
+
+// listJobs retorna os jobs mais recentes primeiro (limit <= 0 retorna todos)
+func listJobs(limit int) ([]Job, error) {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+
+	ids, err := jobIDs(jobsDir())
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+	jobs := make([]Job, 0, len(ids))
+	for _, id := range ids {
+		job, err := readJobMeta(id)
+		if err != nil {
+			continue
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, nil
+}
+
+func readJobMeta(id string) (*Job, error) {
+	meta, err := os.ReadFile(filepath.Join(jobsDir(), id+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var job Job
+	if err := json.Unmarshal(meta, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// loadJob retorna os dados e o conteúdo de um job gravado
+func loadJob(id string) (*Job, []byte, error) {
+	if !jobIDRe.MatchString(id) {
+		return nil, nil, fmt.Errorf("id de job inválido: %s", id)
+	}
+
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+
+	job, err := readJobMeta(id)
+	if os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("job %s não encontrado", id)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(jobsDir(), id+".bin"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("conteúdo do job %s indisponível: %v", id, err)
+	}
+	return job, data, nil
+}
@@ -4,11 +4,36 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runCLI(os.Args[1:]))
+	}
+	serve()
+}
+
+// serve inicia o agente: WebSocket, HTTP e consumo do RabbitMQ
+func serve() {
+	// Retoma a última configuração salva, se houver
+	if config, err := loadConfig(); err == nil {
+		if err := config.Validate(); err != nil {
+			log.Printf("Configuração salva ignorada: %v", err)
+		} else {
+			GlobalConfig = config
+			log.Printf("Configuração carregada de %s (schema: %s)", configPath(), config.SchemaName)
+			go startRabbitMQConsumer()
+		}
+	}
+
 	http.HandleFunc("/ws", wsHandler)
+	http.HandleFunc("/drawer/open", drawerHandler)
 
-	fmt.Println("Print Agent rodando na porta :8089")
-	log.Fatal(http.ListenAndServe(":8089", nil))
+	listen := defaultListen
+	if GlobalConfig != nil && GlobalConfig.Listen != "" {
+		listen = GlobalConfig.Listen
+	}
+	fmt.Printf("Print Agent rodando em %s\n", listen)
+	log.Fatal(http.ListenAndServe(listen, nil))
 }
@@ -0,0 +1,13 @@
+package main
+
+import "github.com/willjrcom/gfood-printer/internal/preview"
+
+// renderPreview desenha como a impressora imprimiria os bytes, em PNG, usando
+// a largura e a tabela de caracteres configuradas para ela
+func renderPreview(printerName string, data []byte) ([]byte, error) {
+	settings := printerSettings(printerName)
+	return preview.PNG(data, preview.Options{
+		DotWidth: settings.profile().DotWidth,
+		CodePage: settings.codePage(),
+	})
+}
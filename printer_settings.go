@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/willjrcom/gfood-printer/internal/escpos"
+	"github.com/willjrcom/gfood-printer/internal/raster"
+	"github.com/willjrcom/gfood-printer/internal/receipt"
+)
+
+// defaultCodePage é usada quando a impressora não tem code_page configurada
+const defaultCodePage = "cp850"
+
+// PrinterConfig guarda as configurações locais de uma impressora. O perfil
+// define o modelo; os demais campos sobrescrevem os valores do perfil.
+type PrinterConfig struct {
+	Profile string `json:"profile,omitempty"` // epson-tm-t20, bematech-mp4200, elgin-i9, daruma-dr800, generic-58, generic-80 ou perfil do config.json
+
+	// Impressora ligada direto ao agente, sem o spooler do sistema:
+	// tcp://192.168.0.50:9100, /dev/usb/lp0, /dev/ttyUSB0 ou COM3. Nesses
+	// transportes o status (papel, tampa, guilhotina) é consultado antes e
+	// depois de cada job, a não ser que check_status seja false.
+	Address     string `json:"address,omitempty"`
+	CheckStatus *bool  `json:"check_status,omitempty"`
+
+	// Reativa (cupsenable) a fila do CUPS quando ela para com jobs pendentes
+	AutoEnable bool `json:"auto_enable,omitempty"`
+
+	// Gaveta ligada à impressora: pino (0 = pino 2, padrão; 1 = pino 5) e
+	// tempos do pulso em ms (padrão 100 ligado / 500 desligado)
+	DrawerPin   *int `json:"drawer_pin,omitempty"`
+	DrawerOnMs  int  `json:"drawer_on_ms,omitempty"`
+	DrawerOffMs int  `json:"drawer_off_ms,omitempty"`
+
+	// Aviso sonoro dos pedidos (veja alerts no config.json): esc_b, epson,
+	// drawer (unidade externa na porta da gaveta, no pino buzzer_pin) ou none
+	Buzzer    string `json:"buzzer,omitempty"`
+	BuzzerPin *int   `json:"buzzer_pin,omitempty"` // 0 = pino 2 (padrão); 1 = pino 5
+
+	CodePage       string `json:"code_page,omitempty"`        // cp850 (padrão), cp860, cp858, cp437, wpc1252
+	CodePageNumber *int   `json:"code_page_number,omitempty"` // n do ESC t n, quando difere do padrão Epson
+
+	// Largura do papel: 58 (32 colunas) ou 80 (48 colunas, padrão). Os demais
+	// campos sobrescrevem os valores do papel para modelos fora do padrão.
+	PaperWidth   int `json:"paper_width,omitempty"`
+	Columns      int `json:"columns,omitempty"`        // caracteres por linha na fonte A
+	ColumnsFontB int `json:"columns_font_b,omitempty"` // caracteres por linha na fonte B
+	DotWidth     int `json:"dot_width,omitempty"`      // pontos imprimíveis por linha
+
+	// Suporte nativo a QR code (GS ( k) e código de barras (GS k). Com false,
+	// os símbolos são gerados pelo agente e enviados como imagem.
+	NativeQR      *bool `json:"native_qr,omitempty"`
+	NativeBarcode *bool `json:"native_barcode,omitempty"`
+
+	// Imagens: raster (GS v 0, padrão) ou column (ESC *) e o pontilhado usado
+	// na conversão (floyd_steinberg, padrão, atkinson ou threshold)
+	ImageMode string `json:"image_mode,omitempty"`
+	Dither    string `json:"dither,omitempty"`
+}
+
+// Validate verifica os valores configurados para a impressora
+func (p PrinterConfig) Validate() error {
+	if p.Address != "" {
+		if _, _, err := parsePrinterAddress(p.Address); err != nil {
+			return err
+		}
+	}
+	if p.CodePage != "" {
+		if _, err := escpos.LookupCodePage(p.CodePage); err != nil {
+			return err
+		}
+	}
+	if p.CodePageNumber != nil && (*p.CodePageNumber < 0 || *p.CodePageNumber > 255) {
+		return fmt.Errorf("code_page_number deve estar entre 0 e 255")
+	}
+	if p.PaperWidth != 0 && p.PaperWidth != 58 && p.PaperWidth != 80 {
+		return fmt.Errorf("paper_width deve ser 58 ou 80")
+	}
+	if p.Columns < 0 || p.ColumnsFontB < 0 || p.DotWidth < 0 {
+		return fmt.Errorf("columns, columns_font_b e dot_width não podem ser negativos")
+	}
+	if !escpos.ValidBuzzer(p.Buzzer) {
+		return fmt.Errorf("buzzer deve ser esc_b, epson, drawer ou none")
+	}
+	if pin := p.buzzerPin(); pin != 0 && pin != 1 {
+		return fmt.Errorf("buzzer_pin deve ser 0 (pino 2) ou 1 (pino 5)")
+	}
+	if p.ImageMode != "" && p.ImageMode != escpos.ImageRaster && p.ImageMode != escpos.ImageColumn {
+		return fmt.Errorf("image_mode deve ser raster ou column")
+	}
+	if _, err := raster.ParseDither(p.Dither); err != nil {
+		return err
+	}
+	pin, _, _ := p.drawerPulse()
+	if err := validateDrawerPulse(pin, p.DrawerOnMs, p.DrawerOffMs); err != nil {
+		return err
+	}
+	return nil
+}
+
+// statusCheck indica se o status da impressora direta deve ser consultado
+func (p PrinterConfig) statusCheck() bool {
+	return p.CheckStatus == nil || *p.CheckStatus
+}
+
+// profile retorna o perfil da impressora (papel, pontos, colunas e comandos
+// suportados) com as sobrescritas da configuração
+func (p PrinterConfig) profile() escpos.Profile {
+	profile, ok := lookupProfile(p.Profile)
+	if !ok {
+		profile = escpos.PaperProfile(p.PaperWidth)
+	} else if p.PaperWidth != 0 && p.PaperWidth != profile.PaperWidth {
+		// Bobina diferente da do perfil: usa a geometria do papel
+		paper := escpos.PaperProfile(p.PaperWidth)
+		profile.PaperWidth, profile.DotWidth = paper.PaperWidth, paper.DotWidth
+		profile.ColumnsFontA, profile.ColumnsFontB = paper.ColumnsFontA, paper.ColumnsFontB
+	}
+	if p.Columns > 0 {
+		profile.ColumnsFontA = p.Columns
+	}
+	if p.ColumnsFontB > 0 {
+		profile.ColumnsFontB = p.ColumnsFontB
+	}
+	if p.DotWidth > 0 {
+		profile.DotWidth = p.DotWidth
+	}
+	if p.NativeQR != nil {
+		profile.NativeQR = *p.NativeQR
+	}
+	if p.NativeBarcode != nil {
+		profile.NativeBarcode = *p.NativeBarcode
+	}
+	if p.ImageMode != "" {
+		profile.ImageMode = p.ImageMode
+	}
+	if p.Buzzer != "" {
+		profile.Buzzer = p.Buzzer
+	}
+	return profile
+}
+
+// codePage retorna a tabela de caracteres da impressora, com o número do
+// ESC t usado pelo modelo
+func (p PrinterConfig) codePage() *escpos.CodePage {
+	profile := p.profile()
+	name := p.CodePage
+	if name == "" {
+		name = profile.CodePage
+	}
+	if name == "" {
+		name = defaultCodePage
+	}
+	cp, err := escpos.LookupCodePage(name)
+	if err != nil {
+		cp, _ = escpos.LookupCodePage(defaultCodePage)
+	}
+	if p.CodePageNumber != nil {
+		return cp.WithNumber(byte(*p.CodePageNumber))
+	}
+	if n := profile.CodePageNumber(cp); n != cp.Number {
+		return cp.WithNumber(n)
+	}
+	return cp
+}
+
+// renderOptions retorna as opções de renderização de documentos da impressora
+func (p PrinterConfig) renderOptions() receipt.Options {
+	opts := receipt.OptionsFromProfile(p.profile())
+	opts.CodePage = p.codePage()
+	opts.Dither, _ = raster.ParseDither(p.Dither)
+	opts.DrawerPin, opts.DrawerOnMs, opts.DrawerOffMs = p.drawerPulse()
+	return opts
+}
+
+// buzzerPin retorna o pino da porta da gaveta usado pelo aviso com buzzer drawer
+func (p PrinterConfig) buzzerPin() int {
+	if p.BuzzerPin != nil {
+		return *p.BuzzerPin
+	}
+	return 0
+}
+
+// drawerPulse retorna o pino e os tempos do pulso da gaveta
+func (p PrinterConfig) drawerPulse() (pin, onMs, offMs int) {
+	if p.DrawerPin != nil {
+		pin = *p.DrawerPin
+	}
+	onMs, offMs = p.DrawerOnMs, p.DrawerOffMs
+	if onMs <= 0 {
+		onMs = receipt.DefaultDrawerOnMs
+	}
+	if offMs <= 0 {
+		offMs = receipt.DefaultDrawerOffMs
+	}
+	return pin, onMs, offMs
+}
+
+// printerSettings retorna a configuração da impressora pelo nome. A entrada
+// "*" vale para as impressoras não listadas; sem perfil na entrada usada, o
+// modelo é detectado pelo nome da impressora.
+func printerSettings(printerName string) PrinterConfig {
+	if GlobalConfig == nil {
+		return PrinterConfig{}
+	}
+	p, ok := GlobalConfig.Printers[printerName]
+	if !ok {
+		p = GlobalConfig.Printers["*"]
+	}
+	if p.Profile == "" {
+		if detected, found := detectProfile(printerName); found {
+			p.Profile = detected
+		}
+	}
+	return p
+}
@@ -2,23 +2,37 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"log"
 	"os/exec"
 	"runtime"
 	"strings"
+	"sync"
 )
 
-// resolvePrinterName verifica se a impressora existe, caso contrário retorna "default"
+// resolvePrinterName traduz estações lógicas para a impressora local e
+// verifica se a impressora existe, caso contrário retorna "default" (ou a
+// impressora virtual marcada como padrão)
 func resolvePrinterName(requestedName string) string {
+	fallback := "default"
+	if name, ok := defaultVirtualPrinter(); ok {
+		fallback = name
+	}
+
+	if printer, ok := stationPrinter(requestedName); ok {
+		log.Printf("Estação [%s]: usando a impressora [%s]", requestedName, printer)
+		requestedName = printer
+	}
+
 	if requestedName == "" || requestedName == "default" {
-		return "default"
+		return fallback
 	}
 
 	printers, err := getPrinters()
 	if err != nil {
-		log.Printf("Fallback: Erro ao listar impressoras: %v. Usando 'default'.", err)
-		return "default"
+		log.Printf("Fallback: Erro ao listar impressoras: %v. Usando '%s'.", err, fallback)
+		return fallback
 	}
 
 	for _, p := range printers {
@@ -27,11 +41,92 @@ func resolvePrinterName(requestedName string) string {
 		}
 	}
 
-	log.Printf("Fallback: Impressora [%s] não encontrada. Usando 'default'.", requestedName)
-	return "default"
+	log.Printf("Fallback: Impressora [%s] não encontrada. Usando '%s'.", requestedName, fallback)
+	return fallback
 }
 
+var missingSpoolerOnce sync.Once
+
+// getPrinters lista as impressoras do sistema seguidas das diretas e das
+// virtuais. Sem o comando de listagem (ex.: lpstat ausente) a lista do
+// sistema fica vazia.
 func getPrinters() ([]string, error) {
+	printers, err := getSystemPrinters()
+	if errors.Is(err, exec.ErrNotFound) {
+		missingSpoolerOnce.Do(func() { log.Printf("Impressoras do sistema indisponíveis: %v", err) })
+		printers, err = []string{}, nil
+	}
+
+	virtual := virtualPrinterNames()
+	if err != nil {
+		if len(virtual) == 0 {
+			return nil, err
+		}
+		log.Printf("Erro ao listar impressoras do sistema: %v. Listando apenas as virtuais.", err)
+		printers = []string{}
+	}
+
+	// Impressoras diretas com o mesmo nome de uma do sistema são usadas no lugar dela
+	listed := map[string]bool{}
+	for _, p := range printers {
+		listed[p] = true
+	}
+	for _, name := range directPrinterNames() {
+		if !listed[name] {
+			printers = append(printers, name)
+		}
+	}
+	return append(printers, virtual...), nil
+}
+
+// Tipos de impressora em get_printers detalhado
+const (
+	PrinterSystem  = "system"
+	PrinterDirect  = "direct"
+	PrinterVirtual = "virtual"
+)
+
+// PrinterInfo descreve uma impressora no get_printers detalhado
+type PrinterInfo struct {
+	Name    string         `json:"name"`
+	Kind    string         `json:"kind"` // system, direct ou virtual
+	Profile string         `json:"profile,omitempty"`
+	Address string         `json:"address,omitempty"`
+	Status  *PrinterStatus `json:"status,omitempty"` // diretas, virtuais e do CUPS
+}
+
+// getPrintersDetailed lista as impressoras com tipo, perfil e status. As
+// impressoras diretas são consultadas em paralelo.
+func getPrintersDetailed() ([]PrinterInfo, error) {
+	names, err := getPrinters()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]PrinterInfo, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		infos[i] = PrinterInfo{Name: name, Kind: PrinterSystem, Profile: printerSettings(name).profile().Name}
+		if v, ok := virtualPrinter(name); ok {
+			status := v.status()
+			infos[i].Kind, infos[i].Status = PrinterVirtual, &status
+		} else if p, ok := directPrinter(name); ok {
+			infos[i].Kind, infos[i].Address = PrinterDirect, p.Address
+			wg.Add(1)
+			go func(info *PrinterInfo, p PrinterConfig) {
+				defer wg.Done()
+				status := pollPrinterStatus(info.Name, p)
+				info.Status = &status
+			}(&infos[i], p)
+		} else {
+			infos[i].Status = cupsSystemStatus(name)
+		}
+	}
+	wg.Wait()
+	return infos, nil
+}
+
+func getSystemPrinters() ([]string, error) {
 	switch runtime.GOOS {
 	case "darwin", "linux":
 		return getPrintersCUPS()
@@ -51,7 +146,7 @@ func getPrintersCUPS() ([]string, error) {
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("erro ao executar lpstat: %v | stderr: %s", err, stderr.String())
+		return nil, fmt.Errorf("erro ao executar lpstat: %w | stderr: %s", err, stderr.String())
 	}
 
 	lines := strings.Split(out.String(), "\n")
@@ -81,7 +176,7 @@ func getPrintersWindows() ([]string, error) {
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("erro ao executar PowerShell: %v | stderr: %s", err, stderr.String())
+		return nil, fmt.Errorf("erro ao executar PowerShell: %w | stderr: %s", err, stderr.String())
 	}
 
 	lines := strings.Split(out.String(), "\n")
@@ -10,7 +10,9 @@ import (
 	"os/exec"
 )
 
-func printToOS(printerName, content string) error {
+// printToOS envia o conteúdo em modo raw pelo lp e retorna o id do job no
+// CUPS (ex.: EPSON_TM_T20-12), usado no acompanhamento da fila
+func printToOS(printerName string, content []byte) (string, error) {
 	var cmd *exec.Cmd
 
 	// Se for "default", usa impressora padrão do sistema
@@ -30,19 +32,12 @@ func printToOS(printerName, content string) error {
 		cmd = exec.Command("lp", "-d", printerName, "-o", "raw")
 	}
 
-	var stderr bytes.Buffer
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = bytes.NewReader(content)
+	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		return err
-	}
-	if _, err := stdin.Write([]byte(content)); err != nil {
-		stdin.Close()
-		return err
-	}
-	stdin.Close()
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("erro ao imprimir via lp: %v | stderr: %s", err, stderr.String())
+		return "", fmt.Errorf("erro ao imprimir via lp: %v | stderr: %s", err, stderr.String())
 	}
-	return nil
+	return parseLPRequestID(stdout.String()), nil
 }
@@ -6,18 +6,19 @@ package main
 import (
 	"fmt"
 	"log"
+	"strconv"
 	"syscall"
 	"unsafe"
 )
 
-func printToOS(printerName, content string) error {
+func printToOS(printerName string, content []byte) (string, error) {
 	var printerToUse string
 
 	// Se for "default", obtém a impressora padrão
 	if printerName == "default" {
 		defaultPrinter, err := getDefaultPrinter()
 		if err != nil {
-			return fmt.Errorf("não foi possível obter impressora padrão: %v", err)
+			return "", fmt.Errorf("não foi possível obter impressora padrão: %v", err)
 		}
 		printerToUse = defaultPrinter
 		log.Printf("Impressora padrão detectada: %s (Raw mode)", printerToUse)
@@ -26,11 +27,20 @@ func printToOS(printerName, content string) error {
 		log.Printf("Imprimindo em [%s] (Raw mode Windows)", printerToUse)
 	}
 
-	return printRawWindows(printerToUse, content)
+	jobID, err := printRawWindows(printerToUse, content)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(uint64(jobID), 10), nil
 }
 
-// printRawWindows usa a API winspool.drv para enviar bytes brutos para a impressora
-func printRawWindows(printerName, content string) error {
+// printRawWindows usa a API winspool.drv para enviar bytes brutos para a
+// impressora e retorna o id do job no spooler
+func printRawWindows(printerName string, content []byte) (uintptr, error) {
+	if len(content) == 0 {
+		return 0, fmt.Errorf("conteúdo vazio")
+	}
+
 	winspool := syscall.NewLazyDLL("winspool.drv")
 	openPrinter := winspool.NewProc("OpenPrinterW")
 	startDocPrinter := winspool.NewProc("StartDocPrinterW")
@@ -44,7 +54,7 @@ func printRawWindows(printerName, content string) error {
 	printerNamePtr, _ := syscall.UTF16PtrFromString(printerName)
 	ret, _, err := openPrinter.Call(uintptr(unsafe.Pointer(printerNamePtr)), uintptr(unsafe.Pointer(&hPrinter)), 0)
 	if ret == 0 {
-		return fmt.Errorf("falha ao abrir impressora [%s]: %v", printerName, err)
+		return 0, fmt.Errorf("falha ao abrir impressora [%s]: %v", printerName, err)
 	}
 	defer closePrinter.Call(hPrinter)
 
@@ -62,24 +72,23 @@ func printRawWindows(printerName, content string) error {
 		Datatype:   dataTypePtr,
 	}
 
-	ret, _, err = startDocPrinter.Call(hPrinter, 1, uintptr(unsafe.Pointer(&di1)))
-	if ret == 0 {
-		return fmt.Errorf("falha ao iniciar documento: %v", err)
+	jobID, _, err := startDocPrinter.Call(hPrinter, 1, uintptr(unsafe.Pointer(&di1)))
+	if jobID == 0 {
+		return 0, fmt.Errorf("falha ao iniciar documento: %v", err)
 	}
 	defer endDocPrinter.Call(hPrinter)
 
 	ret, _, err = startPagePrinter.Call(hPrinter)
 	if ret == 0 {
-		return fmt.Errorf("falha ao iniciar página: %v", err)
+		return 0, fmt.Errorf("falha ao iniciar página: %v", err)
 	}
 	defer endPagePrinter.Call(hPrinter)
 
 	var written uint32
-	contentBytes := []byte(content)
-	ret, _, err = writePrinter.Call(hPrinter, uintptr(unsafe.Pointer(&contentBytes[0])), uintptr(len(contentBytes)), uintptr(unsafe.Pointer(&written)))
+	ret, _, err = writePrinter.Call(hPrinter, uintptr(unsafe.Pointer(&content[0])), uintptr(len(content)), uintptr(unsafe.Pointer(&written)))
 	if ret == 0 {
-		return fmt.Errorf("falha ao escrever na impressora: %v", err)
+		return 0, fmt.Errorf("falha ao escrever na impressora: %v", err)
 	}
 
-	return nil
+	return jobID, nil
 }
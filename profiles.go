@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/willjrcom/gfood-printer/internal/escpos"
+)
+
+var profileNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// ProfileInfo é um perfil do catálogo, marcando os definidos no config.json
+type ProfileInfo struct {
+	escpos.Profile
+	Custom bool `json:"custom"`
+}
+
+// PrinterProfile é o perfil atribuído a uma impressora do sistema
+type PrinterProfile struct {
+	Printer string `json:"printer"`
+	Profile string `json:"profile"`
+	Source  string `json:"source"` // config, detected ou default
+}
+
+// lookupProfile busca o perfil pelo nome: primeiro os definidos no
+// config.json, depois o catálogo embutido
+func lookupProfile(name string) (escpos.Profile, bool) {
+	if name == "" {
+		return escpos.Profile{}, false
+	}
+	if GlobalConfig != nil {
+		if p, ok := GlobalConfig.Profiles[name]; ok {
+			p.Name = name
+			return p.WithDefaults(), true
+		}
+	}
+	return escpos.LookupProfile(name)
+}
+
+// detectProfile identifica o modelo pelo nome da impressora, consultando os
+// trechos de nome (match) dos perfis do config.json e do catálogo
+func detectProfile(printerName string) (string, bool) {
+	if GlobalConfig != nil {
+		name := strings.ToLower(printerName)
+		keys := make([]string, 0, len(GlobalConfig.Profiles))
+		for key := range GlobalConfig.Profiles {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			for _, m := range GlobalConfig.Profiles[key].Match {
+				if m != "" && strings.Contains(name, strings.ToLower(m)) {
+					return key, true
+				}
+			}
+		}
+	}
+	p, ok := escpos.DetectProfile(printerName)
+	return p.Name, ok
+}
+
+// hasProfile indica se o perfil existe na configuração ou no catálogo
+func (c *Config) hasProfile(name string) bool {
+	if _, ok := c.Profiles[name]; ok {
+		return true
+	}
+	_, ok := escpos.LookupProfile(name)
+	return ok
+}
+
+// validateProfile verifica um perfil definido pelo usuário
+func validateProfile(name string, p escpos.Profile) error {
+	if !profileNameRe.MatchString(name) {
+		return fmt.Errorf("nome de perfil inválido: %q (use letras minúsculas, números, '-', '_' e '.')", name)
+	}
+	if p.PaperWidth != 0 && p.PaperWidth != 58 && p.PaperWidth != 80 {
+		return fmt.Errorf("paper_width deve ser 58 ou 80")
+	}
+	if p.DotWidth < 0 || p.ColumnsFontA < 0 || p.ColumnsFontB < 0 {
+		return fmt.Errorf("dot_width, columns_font_a e columns_font_b não podem ser negativos")
+	}
+	if p.ImageMode != "" && p.ImageMode != escpos.ImageRaster && p.ImageMode != escpos.ImageColumn {
+		return fmt.Errorf("image_mode deve ser raster ou column")
+	}
+	if p.Cut != "" && p.Cut != escpos.CutPartial && p.Cut != escpos.CutFull && p.Cut != escpos.CutNone {
+		return fmt.Errorf("cut deve ser partial, full ou none")
+	}
+	if !escpos.ValidBuzzer(p.Buzzer) {
+		return fmt.Errorf("buzzer deve ser esc_b, epson, drawer ou none")
+	}
+	if p.CodePage != "" {
+		if _, err := escpos.LookupCodePage(p.CodePage); err != nil {
+			return err
+		}
+	}
+	for cp, n := range p.CodePages {
+		if _, err := escpos.LookupCodePage(cp); err != nil {
+			return err
+		}
+		if n < 0 || n > 255 {
+			return fmt.Errorf("code_pages: número de %s deve estar entre 0 e 255", cp)
+		}
+	}
+	return nil
+}
+
+// listProfiles retorna o catálogo embutido seguido dos perfis do config.json
+func listProfiles() []ProfileInfo {
+	list := []ProfileInfo{}
+	for _, p := range escpos.Profiles() {
+		if GlobalConfig != nil {
+			if _, ok := GlobalConfig.Profiles[p.Name]; ok {
+				continue // sobrescrito pelo usuário
+			}
+		}
+		list = append(list, ProfileInfo{Profile: p})
+	}
+	if GlobalConfig != nil {
+		for name := range GlobalConfig.Profiles {
+			p, _ := lookupProfile(name)
+			list = append(list, ProfileInfo{Profile: p, Custom: true})
+		}
+	}
+	return list
+}
+
+// printerProfiles informa o perfil usado por cada impressora do sistema
+func printerProfiles() ([]PrinterProfile, error) {
+	printers, err := getPrinters()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]PrinterProfile, 0, len(printers))
+	for _, name := range printers {
+		pp := PrinterProfile{Printer: name, Source: "default"}
+		var configured string
+		if GlobalConfig != nil {
+			p, ok := GlobalConfig.Printers[name]
+			if !ok {
+				p = GlobalConfig.Printers["*"]
+			}
+			configured = p.Profile
+		}
+		if _, detected := detectProfile(name); configured != "" {
+			pp.Source = "config"
+		} else if detected {
+			pp.Source = "detected"
+		}
+		pp.Profile = printerSettings(name).profile().Name
+		result = append(result, pp)
+	}
+	return result, nil
+}
+
+// saveProfile grava um perfil definido pelo usuário no config.json
+func saveProfile(name string, p escpos.Profile) error {
+	if err := validateProfile(name, p); err != nil {
+		return err
+	}
+	p.Name = name
+
+	return updateConfig(func(config *Config) error {
+		if config.Profiles == nil {
+			config.Profiles = map[string]escpos.Profile{}
+		}
+		config.Profiles[name] = p
+		return nil
+	})
+}
+
+// deleteProfile remove um perfil do config.json, desde que nenhuma
+// impressora o use
+func deleteProfile(name string) error {
+	return updateConfig(func(config *Config) error {
+		if _, ok := config.Profiles[name]; !ok {
+			return fmt.Errorf("perfil %s não encontrado", name)
+		}
+		for printer, p := range config.Printers {
+			if p.Profile == name {
+				if _, builtin := escpos.LookupProfile(name); !builtin {
+					return fmt.Errorf("perfil %s em uso pela impressora %s", name, printer)
+				}
+			}
+		}
+		delete(config.Profiles, name)
+		return nil
+	})
+}
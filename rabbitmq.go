@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
@@ -16,32 +18,141 @@ import (
 const maxRetries = 3
 
 var (
-	rabbitService *rabbitmq.RabbitMQ
-	stopChan      chan struct{}
-	retryMap      sync.Map
+	rabbitService  *rabbitmq.RabbitMQ
+	apiClient      *api.Client
+	stopChan       chan struct{}
+	consumerCancel context.CancelFunc
+	retryMap       sync.Map
+	printedMap     sync.Map // deliveryKey → destinos já impressos (map[int]TargetResult)
+
+	consumerMu     sync.Mutex
+	consumerStatus = map[string]*SubscriptionStatus{}
+	consumerError  string
 )
 
+// SubscriptionStatus descreve o estado de uma inscrição para o health
+type SubscriptionStatus struct {
+	Subscription
+	Status string `json:"status"` // active, error, stopped
+	Error  string `json:"error,omitempty"`
+}
+
+func setSubscriptionStatus(sub Subscription, status string, err error) {
+	consumerMu.Lock()
+	defer consumerMu.Unlock()
+
+	s := &SubscriptionStatus{Subscription: sub, Status: status}
+	if err != nil {
+		s.Error = err.Error()
+	}
+	consumerStatus[sub.Exchange+"|"+sub.RoutingKey] = s
+}
+
+func setConsumerError(err error) {
+	consumerMu.Lock()
+	defer consumerMu.Unlock()
+
+	consumerError = ""
+	if err != nil {
+		consumerError = err.Error()
+	}
+}
+
+// resetConsumerStatus marca todas as inscrições configuradas como paradas
+func resetConsumerStatus(subs []Subscription) {
+	consumerMu.Lock()
+	consumerStatus = map[string]*SubscriptionStatus{}
+	consumerMu.Unlock()
+
+	for _, sub := range subs {
+		setSubscriptionStatus(sub, "stopped", nil)
+	}
+}
+
 type PrintMessage struct {
 	Path        string `json:"path"`
 	PrinterName string `json:"printer_name"`
+
+	// Cópias e vários destinos (impressoras, estações ou grupos) por mensagem;
+	// com targets, printer_name é ignorado
+	Copies  int           `json:"copies,omitempty"`
+	Targets []PrintTarget `json:"targets,omitempty"`
+
+	// Conteúdo inline: quando presente, dispensa a busca via Path no backend
+	Content         string          `json:"content,omitempty"`
+	ContentEncoding string          `json:"content_encoding,omitempty"` // base64 ou vazio (texto)
+	ContentType     string          `json:"content_type,omitempty"`
+	Document        json.RawMessage `json:"document,omitempty"` // documento estruturado (receipt)
+
+	// Template local renderizado com os dados JSON (inline ou buscados via Path)
+	Template     string          `json:"template,omitempty"`
+	TemplateData json.RawMessage `json:"template_data,omitempty"`
+}
+
+// deliveryKey identifica a mensagem para o controle de tentativas
+func deliveryKey(d amqp.Delivery) string {
+	return fmt.Sprintf("%x", md5.Sum(d.Body))
+}
+
+// deliveryMessageID retorna o message_id da mensagem ou, na falta dele, o hash do corpo
+func deliveryMessageID(d amqp.Delivery) string {
+	if d.MessageId != "" {
+		return d.MessageId
+	}
+	return deliveryKey(d)
+}
+
+// printedTargets retorna os destinos da mensagem impressos nas tentativas anteriores
+func printedTargets(key string) map[int]TargetResult {
+	if val, ok := printedMap.Load(key); ok {
+		return val.(map[int]TargetResult)
+	}
+	return nil
+}
+
+// rememberPrintedTargets guarda os destinos impressos até a mensagem ser
+// confirmada ou descartada
+func rememberPrintedTargets(key string, results []TargetResult) {
+	printed := map[int]TargetResult{}
+	for i, r := range results {
+		if r.Status == TargetPrinted {
+			printed[i] = r
+		}
+	}
+	printedMap.Store(key, printed)
 }
 
 // nackWithRetry faz Nack com requeue até maxRetries vezes; depois descarta.
-func nackWithRetry(d amqp.Delivery, label string) {
-	key := fmt.Sprintf("%x", md5.Sum(d.Body))
+// Retorna o número de tentativas já feitas e se a mensagem foi descartada.
+func nackWithRetry(d amqp.Delivery, label string) (int, bool) {
+	key := deliveryKey(d)
 	val, _ := retryMap.LoadOrStore(key, 0)
 	count := val.(int)
 
 	if count >= maxRetries {
 		log.Printf("RabbitMQ: Descartando mensagem após %d tentativas [%s]", maxRetries, label)
 		retryMap.Delete(key)
+		printedMap.Delete(key)
 		d.Nack(false, false)
-		return
+		return count + 1, true
 	}
 
 	retryMap.Store(key, count+1)
 	log.Printf("RabbitMQ: Tentativa %d/%d — requeue [%s]", count+1, maxRetries, label)
 	d.Nack(false, true)
+	return count + 1, false
+}
+
+// ackDelivery confirma a mensagem e retorna o número de tentativas usadas
+func ackDelivery(d amqp.Delivery) int {
+	key := deliveryKey(d)
+	attempts := 1
+	if val, ok := retryMap.LoadAndDelete(key); ok {
+		attempts = val.(int) + 1
+	}
+	printedMap.Delete(key)
+	d.Ack(false)
+	return attempts
 }
 
 func startRabbitMQConsumer() {
@@ -53,80 +164,68 @@ func startRabbitMQConsumer() {
 	// Se já houver um consumidor rodando, para ele
 	if stopChan != nil {
 		close(stopChan)
+		consumerCancel()
 		time.Sleep(1 * time.Second)
 	}
 
 	stopChan = make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	consumerCancel = cancel
+
+	// Um cliente por consumidor, lendo a configuração atual a cada chamada; o
+	// transporte HTTP é compartilhado entre eles
+	apiClient = api.NewClient(liveConfig{})
+	handler := &deliveryHandler{ctx: ctx, client: apiClient}
+	stop := stopChan
 
 	go func() {
 		for {
 			select {
-			case <-stopChan:
+			case <-stop:
 				log.Println("RabbitMQ: Parando consumidor")
 				if rabbitService != nil {
 					rabbitService.Close()
 				}
 				return
 			default:
-				if err := connectAndConsume(); err != nil {
+				if err := connectAndConsume(handler, stop); err != nil {
 					log.Printf("RabbitMQ: Erro na conexão (tentando em 5s): %v", err)
+					setConsumerError(err)
 					time.Sleep(5 * time.Second)
-					continue
 				}
-				return
 			}
 		}
 	}()
 }
 
-func connectAndConsume() error {
+func connectAndConsume(handler *deliveryHandler, stop chan struct{}) error {
+	subs := GlobalConfig.GetSubscriptions()
+	resetConsumerStatus(subs)
+
 	var err error
 	rabbitService, err = rabbitmq.NewInstance(GlobalConfig.RabbitMQURL)
 	if err != nil {
 		return err
 	}
+	setConsumerError(nil)
+	handler.service = rabbitService
 
-	// Exchanges (Tópicos) para consumir
-	exchanges := []string{rabbitmq.SHIFT_EX, rabbitmq.GROUP_ITEM_EX, rabbitmq.ORDER_EX}
-
-	for _, ex := range exchanges {
-		msgs, err := rabbitService.ConsumeMessages(ex, GlobalConfig.SchemaName)
+	for _, sub := range subs {
+		msgs, err := rabbitService.ConsumeBinding(sub.Binding())
 		if err != nil {
-			log.Printf("RabbitMQ: Erro ao consumir fila para %s: %v", ex, err)
+			log.Printf("RabbitMQ: Erro ao consumir fila para %s: %v", sub.Exchange, err)
+			setSubscriptionStatus(sub, "error", err)
 			continue
 		}
+		setSubscriptionStatus(sub, "active", nil)
+		log.Printf("RabbitMQ: Consumindo %s (routing key: %s)", sub.Exchange, sub.RoutingKey)
 
-		go func(ex string, deliveries <-chan amqp.Delivery) {
+		go func(h deliveryHandler, sub Subscription, deliveries <-chan amqp.Delivery) {
 			for d := range deliveries {
-				var msg PrintMessage
-				if err := json.Unmarshal(d.Body, &msg); err != nil {
-					log.Printf("RabbitMQ: Erro ao decodificar mensagem: %v", err)
-					d.Nack(false, false) // JSON inválido: descarta direto
-					continue
-				}
-				log.Printf("RabbitMQ: Mensagem recebida para %s: %s", ex, msg.Path)
-
-				// Busca conteúdo via API (timeout de 10s interno no http.Client)
-				content, err := api.FetchPrintContent(GlobalConfig, msg.Path)
-				if err != nil {
-					log.Printf("RabbitMQ: Erro ao buscar conteúdo para ID %s: %v", msg.Path, err)
-					nackWithRetry(d, msg.Path)
-					continue
-				}
-
-				// Imprime
-				printerName := resolvePrinterName(msg.PrinterName)
-				if err := printToOS(printerName, content); err != nil {
-					log.Printf("RabbitMQ: Erro ao imprimir conteúdo para ID %s: %v", msg.Path, err)
-					nackWithRetry(d, msg.Path)
-				} else {
-					log.Printf("RabbitMQ: Impressão enviada com sucesso para ID: %s", msg.Path)
-					key := fmt.Sprintf("%x", md5.Sum(d.Body))
-					retryMap.Delete(key)
-					d.Ack(false)
-				}
+				h.handle(sub, d)
 			}
-		}(ex, msgs)
+			setSubscriptionStatus(sub, "stopped", nil)
+		}(*handler, sub, msgs)
 	}
 
 	// Mantém a conexão aberta até erro ou sinal de parada
@@ -136,7 +235,242 @@ func connectAndConsume() error {
 	select {
 	case amqpErr := <-errChan:
 		return amqpErr
-	case <-stopChan:
+	case <-stop:
 		return nil
 	}
 }
+
+// deliveryHandler processa as mensagens de uma conexão do consumidor
+type deliveryHandler struct {
+	ctx     context.Context // cancelado quando o consumidor é parado
+	service *rabbitmq.RabbitMQ
+	client  *api.Client
+}
+
+// handle busca o conteúdo, imprime e publica o resultado da mensagem
+func (h deliveryHandler) handle(sub Subscription, d amqp.Delivery) {
+	service := h.service
+	result := PrintResult{
+		MessageID:  deliveryMessageID(d),
+		Exchange:   sub.Exchange,
+		ReceivedAt: time.Now(),
+	}
+
+	var msg PrintMessage
+	if err := json.Unmarshal(d.Body, &msg); err != nil {
+		log.Printf("RabbitMQ: Erro ao decodificar mensagem: %v", err)
+		d.Nack(false, false) // JSON inválido: descarta direto
+		result.Outcome = OutcomeFailed
+		result.Error = fmt.Sprintf("mensagem inválida: %v", err)
+		result.Attempts = 1
+		publishPrintResult(service, result)
+		return
+	}
+	log.Printf("RabbitMQ: Mensagem recebida para %s: %s", sub.Exchange, msg.Path)
+	result.Path = msg.Path
+
+	// failed registra a falha; o resultado só é publicado quando a mensagem é descartada
+	failed := func(err error) {
+		attempts, discarded := nackWithRetry(d, msg.Path)
+		if !discarded {
+			return
+		}
+		result.Outcome = OutcomeFailed
+		result.Error = err.Error()
+		result.Attempts = attempts
+		publishPrintResult(service, result)
+	}
+
+	// discard descarta a mensagem sem retry (erro permanente)
+	discard := func(err error) {
+		d.Nack(false, false)
+		retryMap.Delete(deliveryKey(d))
+		printedMap.Delete(deliveryKey(d))
+		result.Outcome = OutcomeFailed
+		result.Error = err.Error()
+		result.Attempts = 1
+		publishPrintResult(service, result)
+	}
+
+	var content []byte
+	var contentType string
+	if !msg.hasInlineContent() && msg.Path == "" {
+		discard(fmt.Errorf("mensagem sem path e sem conteúdo inline"))
+		return
+	}
+
+	if msg.hasInlineContent() {
+		// Conteúdo enviado na própria mensagem
+		inline, inlineType, err := inlineContent(d, msg)
+		if err != nil {
+			log.Printf("RabbitMQ: Conteúdo inline inválido para %s: %v", result.MessageID, err)
+			discard(err)
+			return
+		}
+		log.Printf("RabbitMQ: Usando conteúdo inline (%d bytes, tipo: %s)", len(inline), inlineType)
+		content, contentType = inline, inlineType
+	} else {
+		// Pausa o consumo enquanto o token estiver expirado ou o backend fora do ar
+		var fetched *api.PrintContent
+		authRetried := false
+		for {
+			if err := tokens.Wait(h.ctx); err != nil {
+				d.Nack(false, true)
+				return
+			}
+			if err := h.client.Breaker().Wait(h.ctx); err != nil {
+				d.Nack(false, true)
+				return
+			}
+
+			// Busca conteúdo via API, com retry e backoff para falhas transitórias
+			token := GlobalConfig.GetAccessToken()
+			var err error
+			fetched, err = h.client.FetchPrintContent(h.ctx, msg.Path)
+			if err == nil {
+				break
+			}
+
+			log.Printf("RabbitMQ: Erro ao buscar conteúdo para ID %s: %v", msg.Path, err)
+			switch {
+			case api.IsAuthError(err) && !authRetried:
+				// Token recusado: aguarda a renovação e tenta de novo, mantendo a entrega
+				authRetried = true
+				tokens.Expire(token)
+				continue
+			case api.IsForbidden(err):
+				// Recusado de novo com o token renovado: falta permissão, não adianta repetir
+				discard(err)
+			case api.IsAuthError(err):
+				failed(err)
+			case h.ctx.Err() != nil, errors.Is(err, api.ErrCircuitOpen):
+				// Consumidor parado ou backend fora: devolve sem contar tentativa
+				d.Nack(false, true)
+			case api.IsRetryable(err):
+				failed(err)
+			default:
+				discard(err)
+			}
+			return
+		}
+		log.Printf("RabbitMQ: Conteúdo recebido do backend (%d bytes, tipo: %s)", len(fetched.Data), fetched.ContentType)
+		// Texto em outra codificação vira UTF-8 antes do template, do ajuste de largura e da tabela de caracteres
+		data, err := decodeCharset(fetched.Data, fetched.Charset)
+		if err != nil {
+			discard(err)
+			return
+		}
+		content, contentType = data, fetched.ContentType
+	}
+
+	// Imprime (sem printer_name usa a impressora da routing key ou a padrão da inscrição)
+	requested := msg.PrinterName
+	if requested == "" && sub.Type == "topic" {
+		requested = printerFromRoutingKey(d.RoutingKey)
+	}
+	if requested == "" {
+		requested = sub.Printer
+	}
+
+	// Template local: o conteúdo recebido são os dados do ticket
+	templateName := msg.Template
+	if templateName == "" {
+		templateName = sub.Template
+	}
+
+	// Template aplicado aos dados e documentos renderizados na largura da impressora
+	renderData := func(printerName string, content []byte, contentType string) ([]byte, error) {
+		if templateName != "" {
+			out, err := applyTemplate(templateName, content, printerColumns(printerName))
+			if err != nil {
+				return nil, fmt.Errorf("template %s: %w", templateName, err)
+			}
+			content, contentType = out, ""
+		}
+		return renderContent(printerName, content, contentType)
+	}
+
+	targets, err := normalizeTargets(requested, msg.Copies, msg.Targets)
+	if err != nil {
+		discard(err)
+		return
+	}
+
+	// Roteamento por item: um ticket parcial por estação. Só com template o
+	// conteúdo são os dados JSON do pedido.
+	if routing := GlobalConfig.Routing; len(msg.Targets) == 0 && routing.appliesTo(sub.Exchange) {
+		var parts []orderPart
+		if templateName == "" {
+			err = fmt.Errorf("mensagem sem template")
+		} else {
+			parts, err = routing.splitOrder(content, requested)
+		}
+		switch {
+		case err != nil:
+			log.Printf("RabbitMQ: Roteamento por item ignorado para %s: %v", result.MessageID, err)
+		case len(parts) == 1:
+			targets[0].Printer = parts[0].Station
+		case len(parts) > 1:
+			log.Printf("RabbitMQ: Pedido %s dividido em %d estações", result.MessageID, len(parts))
+			targets = routedTargets(parts, targets[0].Copies, func(printerName string, data []byte) ([]byte, error) {
+				return renderData(printerName, data, contentType)
+			})
+		}
+	}
+
+	// Aviso sonoro nas impressoras de cozinha
+	if GlobalConfig.Alerts.appliesTo(sub.Exchange) {
+		for i := range targets {
+			targets[i].alert = true
+		}
+	}
+
+	base := Job{
+		Source:    SourceRabbitMQ,
+		MessageID: result.MessageID,
+		Exchange:  sub.Exchange,
+		Path:      msg.Path,
+	}
+	// Numa nova tentativa, os destinos que já imprimiram não recebem de novo
+	key := deliveryKey(d)
+	results, err := dispatchTargets(targets, base, func(printerName string) ([]byte, error) {
+		return renderData(printerName, content, contentType)
+	}, printedTargets(key))
+	rememberPrintedTargets(key, results)
+
+	if len(msg.Targets) == 0 {
+		result.Requested = requested
+	}
+	if len(msg.Targets) > 0 || len(results) > 1 {
+		result.Targets = results
+	}
+	for _, r := range results {
+		if r.Status != TargetPrinted {
+			continue
+		}
+		if result.PrinterName == "" {
+			result.PrinterName = r.Printer
+		}
+		if len(r.Printers) > 0 {
+			result.Printers = append(result.Printers, r.Printers...)
+		} else if len(results) > 1 {
+			result.Printers = append(result.Printers, r.Printer)
+		}
+	}
+	if err != nil {
+		if isContentError(err) {
+			// Erro de template ou renderização é permanente
+			log.Printf("RabbitMQ: Erro ao renderizar conteúdo para %s: %v", result.MessageID, err)
+			discard(err)
+			return
+		}
+		log.Printf("RabbitMQ: Erro ao imprimir conteúdo para ID %s: %v", msg.Path, err)
+		failed(err)
+		return
+	}
+
+	log.Printf("RabbitMQ: Impressão enviada com sucesso para ID: %s", msg.Path)
+	result.Outcome = OutcomePrinted
+	result.Attempts = ackDelivery(d)
+	publishPrintResult(service, result)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/willjrcom/gfood-printer/internal/escpos"
+	"github.com/willjrcom/gfood-printer/internal/receipt"
+)
+
+// renderContent converte o conteúdo recebido nos bytes enviados à impressora.
+// Documentos estruturados e imagens (PNG/JPEG) viram ESC/POS na largura da
+// impressora; texto UTF-8 é ajustado à largura e convertido para a tabela de
+// caracteres; o restante segue como está.
+func renderContent(printerName string, data []byte, contentType string) ([]byte, error) {
+	settings := printerSettings(printerName)
+
+	if receipt.IsDocument(contentType, data) {
+		doc, err := receipt.Parse(data)
+		if err != nil {
+			return nil, err
+		}
+		return receipt.Render(doc, settings.renderOptions())
+	}
+
+	if receipt.IsImage(contentType) {
+		return receipt.RenderImage(data, settings.renderOptions())
+	}
+
+	if isPlainText(contentType, data) {
+		text := []byte(receipt.Reflow(string(data), settings.profile().ColumnsFontA))
+		if bytes.IndexFunc(text, func(r rune) bool { return r >= utf8.RuneSelf }) >= 0 {
+			return settings.codePage().EncodeText(text), nil
+		}
+		return text, nil
+	}
+	return data, nil
+}
+
+// printerColumns retorna os caracteres por linha (fonte A) da impressora
+func printerColumns(printerName string) int {
+	return printerSettings(printerName).profile().ColumnsFontA
+}
+
+// isPlainText indica texto UTF-8. Conteúdo binário (application/octet-stream,
+// application/vnd.escpos) nunca é alterado.
+func isPlainText(contentType string, data []byte) bool {
+	contentType = strings.ToLower(contentType)
+	if contentType != "" && !strings.HasPrefix(contentType, "text/") {
+		return false
+	}
+	return utf8.Valid(data)
+}
+
+// decodeCharset converte para UTF-8 o conteúdo recebido em outra codificação
+// (parâmetro charset do Content-Type): ISO-8859-1 e as tabelas de caracteres
+// conhecidas (windows-1252, cp850, ...). UTF-8 e ASCII seguem como estão.
+func decodeCharset(data []byte, charset string) ([]byte, error) {
+	name := strings.ToLower(strings.TrimSpace(charset))
+	var decode func(b byte) rune
+	switch name {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return data, nil
+	case "iso-8859-1", "iso8859-1", "iso_8859-1", "latin1", "l1":
+		decode = func(b byte) rune { return rune(b) }
+	default:
+		cp, err := escpos.LookupCodePage(name)
+		if err != nil {
+			return nil, fmt.Errorf("charset não suportado: %s", charset)
+		}
+		decode = cp.Decode
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(data) + len(data)/4)
+	for _, b := range data {
+		sb.WriteRune(decode(b))
+	}
+	return []byte(sb.String()), nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecodeCharset(t *testing.T) {
+	tests := []struct {
+		charset string
+		data    []byte
+		want    string
+	}{
+		{"", []byte("Pão"), "Pão"},
+		{"UTF-8", []byte("Pão"), "Pão"},
+		{"iso-8859-1", []byte{'P', 0xE3, 'o', ' ', 0xE7, 0xE9}, "Pão çé"},
+		{"Latin1", []byte{0xBA, 0xAA}, "ºª"},
+		{"windows-1252", []byte{0x80, ' ', 0xE3}, "€ ã"},
+		{"cp850", []byte{'P', 0xC6, 'o', ' ', 0x87}, "Pão ç"},
+		{"us-ascii", []byte("R$ 10,00\n"), "R$ 10,00\n"},
+	}
+	for _, tt := range tests {
+		got, err := decodeCharset(tt.data, tt.charset)
+		if err != nil {
+			t.Errorf("%s: %v", tt.charset, err)
+			continue
+		}
+		if !bytes.Equal(got, []byte(tt.want)) {
+			t.Errorf("%s: got %q, want %q", tt.charset, got, tt.want)
+		}
+	}
+
+	if _, err := decodeCharset([]byte("x"), "shift_jis"); err == nil {
+		t.Error("charset desconhecido aceito")
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/willjrcom/gfood-printer/internal/service/rabbitmq"
+)
+
+const resultConfirmTimeout = 5 * time.Second
+
+// Resultados possíveis de uma impressão
+const (
+	OutcomePrinted = "printed"
+	OutcomeFailed  = "failed"
+)
+
+// PrintResult é o evento publicado em print.result para que o backend saiba
+// se o ticket foi realmente impresso
+type PrintResult struct {
+	MessageID   string   `json:"message_id"`
+	Exchange    string   `json:"exchange"`
+	Path        string   `json:"path"`
+	PrinterName string   `json:"printer_name"`        // impressora que imprimiu
+	Requested   string   `json:"requested,omitempty"` // impressora, estação ou grupo pedido
+	Printers    []string `json:"printers,omitempty"`  // vários destinos ou broadcast: todas as que imprimiram
+
+	// Situação de cada destino quando a mensagem tem targets
+	Targets    []TargetResult `json:"targets,omitempty"`
+	Outcome    string         `json:"outcome"`
+	Error      string         `json:"error,omitempty"`
+	Attempts   int            `json:"attempts"`
+	ReceivedAt time.Time      `json:"received_at"`
+	FinishedAt time.Time      `json:"finished_at"`
+	AgentID    string         `json:"agent_id"`
+	SchemaName string         `json:"schema_name"`
+}
+
+// publishPrintResult envia o resultado para a exchange print.result do schema,
+// aguardando a confirmação do broker. Falhas são apenas registradas no log.
+func publishPrintResult(service *rabbitmq.RabbitMQ, result PrintResult) {
+	if service == nil || GlobalConfig == nil {
+		return
+	}
+
+	result.AgentID = GlobalConfig.GetAgentID()
+	result.SchemaName = GlobalConfig.SchemaName
+	if result.FinishedAt.IsZero() {
+		result.FinishedAt = time.Now()
+	}
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		log.Printf("RabbitMQ: Erro ao serializar resultado [%s]: %v", result.MessageID, err)
+		return
+	}
+
+	msg := amqp.Publishing{
+		ContentType: "application/json",
+		MessageId:   result.MessageID,
+		Timestamp:   result.FinishedAt,
+		AppId:       result.AgentID,
+		Body:        body,
+	}
+	if err := service.PublishWithConfirm(rabbitmq.RESULT_EX, result.SchemaName, msg, resultConfirmTimeout); err != nil {
+		log.Printf("RabbitMQ: Erro ao publicar resultado [%s] (%s): %v", result.MessageID, result.Outcome, err)
+		return
+	}
+	log.Printf("RabbitMQ: Resultado publicado [%s]: %s", result.MessageID, result.Outcome)
+}
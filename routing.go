@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/willjrcom/gfood-printer/internal/receipt"
+	"github.com/willjrcom/gfood-printer/internal/service/rabbitmq"
+)
+
+// RoutingConfig divide os pedidos entre as estações pelos itens: cada estação
+// recebe um ticket só com os próprios itens (bebidas no bar, pizzas no forno),
+// com um cabeçalho identificando o pedido. Vale para as mensagens com
+// template, em que o conteúdo são os dados JSON do pedido.
+type RoutingConfig struct {
+	Exchanges      []string      `json:"exchanges,omitempty"`       // padrão: print.group.item
+	ItemsField     string        `json:"items_field,omitempty"`     // lista de itens nos dados (padrão items)
+	OrderField     string        `json:"order_field,omitempty"`     // identificação do pedido no cabeçalho (padrão number)
+	DefaultStation string        `json:"default_station,omitempty"` // itens sem regra; vazio = destino da mensagem
+	Rules          []RoutingRule `json:"rules"`
+}
+
+// RoutingRule envia à estação os itens em que o campo é igual a um dos
+// valores (equals), contém o texto (contains) ou que têm a tag. A primeira
+// regra que casar vale.
+type RoutingRule struct {
+	Field    string   `json:"field,omitempty"` // campo do item; aceita caminho (product.category)
+	Equals   []string `json:"equals,omitempty"`
+	Contains string   `json:"contains,omitempty"`
+	Tag      string   `json:"tag,omitempty"` // valor na lista tags do item
+	Station  string   `json:"station"`
+}
+
+// Validate verifica as regras de roteamento
+func (rc *RoutingConfig) Validate() error {
+	for i, rule := range rc.Rules {
+		if rule.Station == "" {
+			return fmt.Errorf("regra %d sem station", i+1)
+		}
+		if len(rule.Equals) == 0 && rule.Contains == "" && rule.Tag == "" {
+			return fmt.Errorf("regra %d sem condição (equals, contains ou tag)", i+1)
+		}
+		if (len(rule.Equals) > 0 || rule.Contains != "") && rule.Field == "" {
+			return fmt.Errorf("regra %d sem field", i+1)
+		}
+	}
+	return nil
+}
+
+// hasTemplate indica se alguma inscrição das exchanges roteadas aplica um
+// template, sem o qual o conteúdo não são os dados do pedido
+func (rc *RoutingConfig) hasTemplate(subs []Subscription) bool {
+	for _, s := range subs {
+		if s.Template != "" && rc.appliesTo(s.Exchange) {
+			return true
+		}
+	}
+	return false
+}
+
+// appliesTo indica se as mensagens da exchange são roteadas por item
+func (rc *RoutingConfig) appliesTo(exchange string) bool {
+	if rc == nil || len(rc.Rules) == 0 {
+		return false
+	}
+	exchanges := rc.Exchanges
+	if len(exchanges) == 0 {
+		exchanges = []string{rabbitmq.GROUP_ITEM_EX}
+	}
+	for _, ex := range exchanges {
+		if ex == exchange {
+			return true
+		}
+	}
+	return false
+}
+
+func (rc *RoutingConfig) itemsField() string {
+	if rc.ItemsField != "" {
+		return rc.ItemsField
+	}
+	return "items"
+}
+
+func (rc *RoutingConfig) orderField() string {
+	if rc.OrderField != "" {
+		return rc.OrderField
+	}
+	return "number"
+}
+
+// matches indica se o item atende à regra
+func (rule RoutingRule) matches(item map[string]interface{}) bool {
+	if rule.Tag != "" {
+		tags, _ := item["tags"].([]interface{})
+		for _, tag := range tags {
+			if strings.EqualFold(routingString(tag), rule.Tag) {
+				return true
+			}
+		}
+		if rule.Field == "" {
+			return false
+		}
+	}
+	value, ok := lookupField(item, rule.Field)
+	if !ok {
+		return false
+	}
+	text := routingString(value)
+	for _, v := range rule.Equals {
+		if strings.EqualFold(text, v) {
+			return true
+		}
+	}
+	return rule.Contains != "" && strings.Contains(strings.ToLower(text), strings.ToLower(rule.Contains))
+}
+
+// lookupField busca o campo pelo caminho separado por pontos
+func lookupField(data map[string]interface{}, path string) (interface{}, bool) {
+	var value interface{} = data
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+func routingString(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// orderPart é o ticket parcial de uma estação
+type orderPart struct {
+	Station     string
+	Data        []byte // dados do pedido só com os itens da estação
+	Order       string // identificação do pedido
+	Part, Parts int
+}
+
+// splitOrder divide os dados do pedido pelas estações das regras, na ordem
+// em que aparecem nos itens. Itens sem regra vão para fallback. Cada parte
+// recebe o campo routing (station, part, parts e order) para uso no template.
+func (rc *RoutingConfig) splitOrder(data []byte, fallback string) ([]orderPart, error) {
+	var order map[string]interface{}
+	if err := json.Unmarshal(data, &order); err != nil {
+		return nil, fmt.Errorf("dados do pedido não são um objeto JSON: %v", err)
+	}
+	items, ok := order[rc.itemsField()].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("dados do pedido sem a lista %s", rc.itemsField())
+	}
+
+	stations := []string{}
+	byStation := map[string][]interface{}{}
+	for _, raw := range items {
+		station := rc.DefaultStation
+		if station == "" {
+			station = fallback
+		}
+		if item, ok := raw.(map[string]interface{}); ok {
+			for _, rule := range rc.Rules {
+				if rule.matches(item) {
+					station = rule.Station
+					break
+				}
+			}
+		}
+		if _, seen := byStation[station]; !seen {
+			stations = append(stations, station)
+		}
+		byStation[station] = append(byStation[station], raw)
+	}
+
+	orderID := ""
+	if v, ok := lookupField(order, rc.orderField()); ok {
+		orderID = routingString(v)
+	}
+
+	parts := make([]orderPart, 0, len(stations))
+	for i, station := range stations {
+		partial := make(map[string]interface{}, len(order)+1)
+		for k, v := range order {
+			partial[k] = v
+		}
+		partial[rc.itemsField()] = byStation[station]
+		partial["routing"] = map[string]interface{}{
+			"station": targetLabel(station),
+			"part":    i + 1,
+			"parts":   len(stations),
+			"order":   orderID,
+		}
+		encoded, err := json.Marshal(partial)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, orderPart{Station: station, Data: encoded, Order: orderID, Part: i + 1, Parts: len(stations)})
+	}
+	return parts, nil
+}
+
+// header identifica o pedido no topo do ticket parcial
+func (p orderPart) header(printerName string) ([]byte, error) {
+	title := "PEDIDO"
+	if p.Order != "" {
+		title = "PEDIDO #" + p.Order
+	}
+	doc := &receipt.Document{Elements: []receipt.Element{
+		{Type: "text", Text: title, Align: "center", Size: "double", Bold: true},
+		{Type: "text", Text: fmt.Sprintf("%s - parte %d/%d", strings.ToUpper(targetLabel(p.Station)), p.Part, p.Parts), Align: "center", Bold: true},
+		{Type: "separator", Char: "="},
+	}}
+	return receipt.Render(doc, printerSettings(printerName).renderOptions())
+}
+
+// routedTargets monta um destino por estação, renderizando os dados parciais
+// com o cabeçalho do pedido
+func routedTargets(parts []orderPart, copies int, render func(printerName string, data []byte) ([]byte, error)) []PrintTarget {
+	targets := make([]PrintTarget, len(parts))
+	for i, part := range parts {
+		part := part
+		targets[i] = PrintTarget{Printer: part.Station, Copies: copies, render: func(printerName string) ([]byte, error) {
+			header, err := part.header(printerName)
+			if err != nil {
+				return nil, err
+			}
+			body, err := render(printerName, part.Data)
+			if err != nil {
+				return nil, err
+			}
+			return append(header, body...), nil
+		}}
+	}
+	return targets
+}
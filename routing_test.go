@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSplitOrder(t *testing.T) {
+	kitchen := RoutingRule{Field: "category", Equals: []string{"Pratos", "Lanches"}, Station: "cozinha"}
+	bar := RoutingRule{Tag: "bebida", Station: "bar"}
+	dessert := RoutingRule{Tag: "doce", Field: "product.name", Contains: "sorvete", Station: "sobremesa"}
+
+	type part struct {
+		station string
+		items   []string // name de cada item; "-" para itens que não são objeto
+	}
+	tests := []struct {
+		name     string
+		rc       RoutingConfig
+		data     string
+		fallback string
+		want     []part
+	}{
+		{
+			"station order follows item order",
+			RoutingConfig{Rules: []RoutingRule{kitchen, bar}},
+			`{"number": 42, "items": [
+				{"name": "Suco", "tags": ["bebida"]},
+				{"name": "X-Burguer", "category": "lanches"},
+				{"name": "Cerveja", "tags": ["BEBIDA"]}]}`,
+			"caixa",
+			[]part{{"bar", []string{"Suco", "Cerveja"}}, {"cozinha", []string{"X-Burguer"}}},
+		},
+		{
+			"tag only rule ignores the fields",
+			RoutingConfig{Rules: []RoutingRule{bar}},
+			`{"items": [
+				{"name": "Água", "category": "bebida"},
+				{"name": "Refri", "tags": ["bebida"]}]}`,
+			"caixa",
+			[]part{{"caixa", []string{"Água"}}, {"bar", []string{"Refri"}}},
+		},
+		{
+			"tag and field rule matches either",
+			RoutingConfig{Rules: []RoutingRule{dessert}},
+			`{"items": [
+				{"name": "Pudim", "tags": ["doce"]},
+				{"name": "Sorvete", "product": {"name": "Sorvete de creme"}},
+				{"name": "Pastel", "product": {"name": "Pastel de carne"}}]}`,
+			"caixa",
+			[]part{{"sobremesa", []string{"Pudim", "Sorvete"}}, {"caixa", []string{"Pastel"}}},
+		},
+		{
+			"first matching rule wins",
+			RoutingConfig{Rules: []RoutingRule{bar, kitchen}},
+			`{"items": [{"name": "Caipirinha", "category": "pratos", "tags": ["bebida"]}]}`,
+			"caixa",
+			[]part{{"bar", []string{"Caipirinha"}}},
+		},
+		{
+			"non object items go to the fallback",
+			RoutingConfig{Rules: []RoutingRule{kitchen}},
+			`{"items": ["Batata", 3, {"name": "Prato feito", "category": "pratos"}]}`,
+			"caixa",
+			[]part{{"caixa", []string{"-", "-"}}, {"cozinha", []string{"Prato feito"}}},
+		},
+		{
+			"default station over the fallback",
+			RoutingConfig{DefaultStation: "expedicao", Rules: []RoutingRule{kitchen}},
+			`{"items": [{"name": "Guardanapo"}]}`,
+			"caixa",
+			[]part{{"expedicao", []string{"Guardanapo"}}},
+		},
+		{
+			"empty fallback",
+			RoutingConfig{Rules: []RoutingRule{kitchen}},
+			`{"items": [{"name": "Guardanapo"}]}`,
+			"",
+			[]part{{"", []string{"Guardanapo"}}},
+		},
+		{
+			"custom items field",
+			RoutingConfig{ItemsField: "products", Rules: []RoutingRule{bar}},
+			`{"products": [{"name": "Chopp", "tags": ["bebida"]}]}`,
+			"caixa",
+			[]part{{"bar", []string{"Chopp"}}},
+		},
+	}
+	for _, tt := range tests {
+		parts, err := tt.rc.splitOrder([]byte(tt.data), tt.fallback)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var got []part
+		for _, p := range parts {
+			var decoded map[string]interface{}
+			if err := json.Unmarshal(p.Data, &decoded); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			items, _ := decoded[tt.rc.itemsField()].([]interface{})
+			names := []string{}
+			for _, item := range items {
+				if m, ok := item.(map[string]interface{}); ok {
+					names = append(names, m["name"].(string))
+				} else {
+					names = append(names, "-")
+				}
+			}
+			got = append(got, part{p.Station, names})
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %v\nwant %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSplitOrderRouting(t *testing.T) {
+	tests := []struct {
+		name   string
+		rc     RoutingConfig
+		header string // campos do pedido antes da lista de itens
+		order  string
+	}{
+		{"numeric order", RoutingConfig{}, `"number": 1042,`, "1042"},
+		{"text order", RoutingConfig{}, `"number": "A-17",`, "A-17"},
+		{"nested order field", RoutingConfig{OrderField: "order.code"}, `"order": {"code": "77"},`, "77"},
+		{"no order", RoutingConfig{}, ``, ""},
+	}
+	items := `[{"name": "Suco", "tags": ["bebida"]}, {"name": "Pizza", "category": "pratos"}, {"name": "Pão"}]`
+	rules := []RoutingRule{
+		{Tag: "bebida", Station: "bar"},
+		{Field: "category", Equals: []string{"pratos"}, Station: "cozinha"},
+	}
+	stations := []string{"bar", "cozinha", "default"}
+
+	for _, tt := range tests {
+		tt.rc.Rules = rules
+		parts, err := tt.rc.splitOrder([]byte(`{`+tt.header+`"items": `+items+`}`), "")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(parts) != len(stations) {
+			t.Fatalf("%s: %d parts, want %d", tt.name, len(parts), len(stations))
+		}
+		for i, p := range parts {
+			if p.Part != i+1 || p.Parts != len(stations) || p.Order != tt.order {
+				t.Errorf("%s: part %+v", tt.name, p)
+			}
+			var decoded struct {
+				Routing map[string]interface{} `json:"routing"`
+			}
+			json.Unmarshal(p.Data, &decoded)
+			want := map[string]interface{}{
+				"station": stations[i],
+				"part":    float64(i + 1),
+				"parts":   float64(len(stations)),
+				"order":   tt.order,
+			}
+			if !reflect.DeepEqual(decoded.Routing, want) {
+				t.Errorf("%s: routing %v, want %v", tt.name, decoded.Routing, want)
+			}
+		}
+	}
+}
+
+func TestSplitOrderInvalid(t *testing.T) {
+	rc := RoutingConfig{Rules: []RoutingRule{{Tag: "bebida", Station: "bar"}}}
+	for _, data := range []string{`[]`, `{"items": {}}`, `{"itens": []}`, `não é json`} {
+		if _, err := rc.splitOrder([]byte(data), "caixa"); err == nil {
+			t.Errorf("%s accepted", data)
+		}
+	}
+}
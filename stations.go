@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+var stationNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// StationInfo é uma estação lógica (cozinha, bar, caixa) e a impressora local
+// que a atende. Assim o backend envia para a estação sem conhecer o nome da
+// fila em cada máquina.
+type StationInfo struct {
+	Name      string `json:"name"`
+	Printer   string `json:"printer"`
+	Available bool   `json:"available"` // a impressora está na lista de get_printers
+}
+
+// validateStation verifica o nome da estação e a impressora associada
+func validateStation(c *Config, name, printer string) error {
+	if !stationNameRe.MatchString(name) || name == "default" {
+		return fmt.Errorf("nome de estação inválido: %q (use letras minúsculas, números, - e _)", name)
+	}
+	if printer == "" {
+		return fmt.Errorf("estação %s sem impressora", name)
+	}
+	if _, ok := c.Stations[printer]; ok {
+		return fmt.Errorf("estação %s aponta para outra estação (%s)", name, printer)
+	}
+	return nil
+}
+
+// stationPrinter retorna a impressora local da estação
+func stationPrinter(name string) (string, bool) {
+	if GlobalConfig == nil {
+		return "", false
+	}
+	printer, ok := GlobalConfig.Stations[name]
+	return printer, ok
+}
+
+// listStations lista as estações configuradas, indicando se a impressora existe
+func listStations() ([]StationInfo, error) {
+	if GlobalConfig == nil {
+		return []StationInfo{}, nil
+	}
+	printers, err := getPrinters()
+	if err != nil {
+		return nil, err
+	}
+	available := map[string]bool{"default": true}
+	for _, p := range printers {
+		available[p] = true
+	}
+
+	stations := make([]StationInfo, 0, len(GlobalConfig.Stations))
+	for name, printer := range GlobalConfig.Stations {
+		stations = append(stations, StationInfo{Name: name, Printer: printer, Available: available[printer]})
+	}
+	sort.Slice(stations, func(i, j int) bool { return stations[i].Name < stations[j].Name })
+	return stations, nil
+}
+
+// saveStation associa a estação a uma impressora local no config.json
+func saveStation(name, printer string) error {
+	return updateConfig(func(config *Config) error {
+		if config.Stations == nil {
+			config.Stations = map[string]string{}
+		}
+		if err := validateStation(config, name, printer); err != nil {
+			return err
+		}
+		config.Stations[name] = printer
+		return nil
+	})
+}
+
+// deleteStation remove a estação do config.json
+func deleteStation(name string) error {
+	return updateConfig(func(config *Config) error {
+		if _, ok := config.Stations[name]; !ok {
+			return fmt.Errorf("estação %s não encontrada", name)
+		}
+		delete(config.Stations, name)
+		return nil
+	})
+}
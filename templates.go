@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/willjrcom/gfood-printer/internal/receipt"
+)
+
+const (
+	templatesDirName  = "templates"
+	templateExtension = ".tmpl"
+)
+
+var templateNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// templatesDir retorna a pasta de templates ao lado do executável
+func templatesDir() string {
+	return filepath.Join(appDir(), templatesDirName)
+}
+
+func templatePath(name string) (string, error) {
+	if !templateNamePattern.MatchString(name) || strings.Contains(name, "..") {
+		return "", fmt.Errorf("nome de template inválido: %s", name)
+	}
+	return filepath.Join(templatesDir(), name+templateExtension), nil
+}
+
+// listTemplates retorna os nomes dos templates salvos
+func listTemplates() ([]string, error) {
+	entries, err := os.ReadDir(templatesDir())
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), templateExtension) {
+			names = append(names, strings.TrimSuffix(e.Name(), templateExtension))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func readTemplate(name string) (string, error) {
+	path, err := templatePath(name)
+	if err != nil {
+		return "", err
+	}
+	text, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("template não encontrado: %s", name)
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(text), nil
+}
+
+// saveTemplate valida e grava o template na pasta de templates
+func saveTemplate(name, text string) error {
+	path, err := templatePath(name)
+	if err != nil {
+		return err
+	}
+	if _, err := receipt.ParseTemplate(name, text, receipt.DefaultOptions.Columns); err != nil {
+		return fmt.Errorf("template inválido: %v", err)
+	}
+	if err := os.MkdirAll(templatesDir(), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(text), 0644)
+}
+
+func deleteTemplate(name string) error {
+	path, err := templatePath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// applyTemplate renderiza o template com os dados JSON na largura informada.
+// O resultado pode ser texto com comandos ESC/POS ou um documento estruturado.
+func applyTemplate(name string, data []byte, width int) ([]byte, error) {
+	text, err := readTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := receipt.ParseTemplate(name, text, width)
+	if err != nil {
+		return nil, fmt.Errorf("template %s inválido: %v", name, err)
+	}
+	out, err := tmpl.Execute(data)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao executar template %s: %v", name, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Eventos enviados aos navegadores conectados sobre o ciclo de vida do token
+const (
+	EventTokenExpired = "token_expired"
+	EventTokenRenewed = "token_renewed"
+)
+
+const (
+	tokenRefreshInterval = 30 * time.Second
+	tokenRefreshTimeout  = 15 * time.Second
+)
+
+// tokenManager pausa as chamadas ao backend enquanto o access_token estiver
+// expirado e libera todas assim que um novo token for recebido
+type tokenManager struct {
+	mu      sync.Mutex
+	expired bool
+	renewed chan struct{} // fechado quando o token é renovado
+}
+
+var tokens = &tokenManager{}
+
+// Expired indica se o token atual foi recusado pelo backend
+func (t *tokenManager) Expired() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.expired
+}
+
+// Expire marca o token como expirado, caso ainda seja o token recusado, e
+// dispara a renovação pelo endpoint configurado e pelos navegadores conectados
+func (t *tokenManager) Expire(rejected string) {
+	config := GlobalConfig
+	if config == nil {
+		return
+	}
+
+	t.mu.Lock()
+	if t.expired || config.GetAccessToken() != rejected {
+		t.mu.Unlock()
+		return
+	}
+	t.expired = true
+	t.renewed = make(chan struct{})
+	renewed := t.renewed
+	t.mu.Unlock()
+
+	log.Println("Token: access_token recusado pelo backend, consumo pausado até a renovação")
+	sent := broadcastEvent(Event{Event: EventTokenExpired})
+	log.Printf("Token: Evento %s enviado para %d navegador(es)", EventTokenExpired, sent)
+
+	if config.TokenRefreshPath != "" {
+		go t.refreshLoop(renewed)
+	}
+}
+
+// refreshLoop tenta renovar o token no backend até conseguir ou até outro
+// caminho (navegador, nova configuração) renovar antes. A configuração é lida
+// a cada tentativa, já que as alterações trocam o GlobalConfig inteiro.
+func (t *tokenManager) refreshLoop(renewed chan struct{}) {
+	for {
+		client, config := apiClient, GlobalConfig
+		if client != nil && config != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), tokenRefreshTimeout)
+			token, err := client.RefreshAccessToken(ctx, config.TokenRefreshPath, config.RefreshToken)
+			cancel()
+			if err == nil {
+				log.Println("Token: access_token renovado pelo endpoint de refresh")
+				t.Renew(token)
+				return
+			}
+			log.Printf("Token: Erro ao renovar access_token (tentando em %s): %v", tokenRefreshInterval, err)
+		}
+
+		select {
+		case <-renewed:
+			return
+		case <-time.After(tokenRefreshInterval):
+		}
+	}
+}
+
+// Renew aplica o novo token, salva a configuração e retoma o consumo
+func (t *tokenManager) Renew(token string) {
+	// Sob o lock da configuração, para uma alteração em andamento não gravar o token antigo
+	configMu.Lock()
+	config := GlobalConfig
+	if config == nil {
+		configMu.Unlock()
+		return
+	}
+	config.SetAccessToken(token)
+	if err := saveConfig(config); err != nil {
+		log.Printf("Token: Erro ao salvar configuração: %v", err)
+	}
+	configMu.Unlock()
+
+	t.mu.Lock()
+	wasExpired := t.expired
+	if t.expired {
+		t.expired = false
+		close(t.renewed)
+	}
+	t.mu.Unlock()
+
+	if wasExpired {
+		log.Println("Token: Consumo retomado com o novo access_token")
+		broadcastEvent(Event{Event: EventTokenRenewed})
+	}
+}
+
+// Wait bloqueia enquanto o token estiver expirado
+func (t *tokenManager) Wait(ctx context.Context) error {
+	t.mu.Lock()
+	if !t.expired {
+		t.mu.Unlock()
+		return nil
+	}
+	renewed := t.renewed
+	t.mu.Unlock()
+
+	select {
+	case <-renewed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
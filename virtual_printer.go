@@ -0,0 +1,185 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/willjrcom/gfood-printer/internal/escpos"
+)
+
+const spoolDirName = "spool"
+
+// Falhas simuladas pelas impressoras virtuais
+const (
+	SimulatePaperOut = "paper_out"
+	SimulateOffline  = "offline"
+)
+
+var virtualNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// VirtualPrinter é uma impressora que grava cada job em uma pasta, para testes
+// sem impressora física e para arquivar o que foi impresso
+type VirtualPrinter struct {
+	Dir       string  `json:"dir,omitempty"`        // padrão: spool/<nome> ao lado do executável
+	Default   bool    `json:"default,omitempty"`    // recebe as impressões para "default" e impressoras não encontradas
+	LatencyMs int     `json:"latency_ms,omitempty"` // atraso simulado de cada impressão
+	Simulate  string  `json:"simulate,omitempty"`   // paper_out ou offline
+	FailRate  float64 `json:"fail_rate,omitempty"`  // chance de falha (0 a 1) com simulate; 0 = sempre
+}
+
+// spoolMeta é o arquivo .json gravado ao lado dos bytes de cada job
+type spoolMeta struct {
+	JobID     string    `json:"job_id"`
+	Printer   string    `json:"printer"`
+	Source    string    `json:"source"`
+	MessageID string    `json:"message_id,omitempty"`
+	Exchange  string    `json:"exchange,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	Size      int       `json:"size"`
+	SHA256    string    `json:"sha256"`
+	WrittenAt time.Time `json:"written_at"`
+}
+
+// Validate verifica a configuração da impressora virtual
+func (v VirtualPrinter) Validate() error {
+	if v.LatencyMs < 0 {
+		return fmt.Errorf("latency_ms não pode ser negativo")
+	}
+	if v.Simulate != "" && v.Simulate != SimulatePaperOut && v.Simulate != SimulateOffline {
+		return fmt.Errorf("simulate deve ser paper_out ou offline")
+	}
+	if v.FailRate < 0 || v.FailRate > 1 {
+		return fmt.Errorf("fail_rate deve estar entre 0 e 1")
+	}
+	return nil
+}
+
+// dir retorna a pasta onde os jobs da impressora são gravados
+func (v VirtualPrinter) dir(name string) string {
+	if v.Dir != "" {
+		return v.Dir
+	}
+	return filepath.Join(appDir(), spoolDirName, name)
+}
+
+// simulatedError retorna a falha configurada, respeitando fail_rate
+func (v VirtualPrinter) simulatedError() error {
+	if v.Simulate == "" || (v.FailRate > 0 && rand.Float64() >= v.FailRate) {
+		return nil
+	}
+	switch v.Simulate {
+	case SimulatePaperOut:
+		return fmt.Errorf("impressora sem papel (simulado)")
+	default:
+		return fmt.Errorf("impressora offline (simulado)")
+	}
+}
+
+// print grava os bytes do job e o arquivo de metadados na pasta da impressora
+func (v VirtualPrinter) print(job *Job, data []byte) error {
+	if v.LatencyMs > 0 {
+		time.Sleep(time.Duration(v.LatencyMs) * time.Millisecond)
+	}
+	if err := v.simulatedError(); err != nil {
+		return err
+	}
+
+	dir := v.dir(job.Printer)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("erro ao criar pasta da impressora virtual: %v", err)
+	}
+
+	sum := sha256.Sum256(data)
+	meta := spoolMeta{
+		JobID:     job.ID,
+		Printer:   job.Printer,
+		Source:    job.Source,
+		MessageID: job.MessageID,
+		Exchange:  job.Exchange,
+		Path:      job.Path,
+		Size:      len(data),
+		SHA256:    hex.EncodeToString(sum[:]),
+		WrittenAt: time.Now(),
+	}
+	sidecar, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	base := filepath.Join(dir, job.ID)
+	if err := os.WriteFile(base+".bin", data, 0644); err != nil {
+		return fmt.Errorf("erro ao gravar job na impressora virtual: %v", err)
+	}
+	if err := os.WriteFile(base+".json", sidecar, 0644); err != nil {
+		return fmt.Errorf("erro ao gravar metadados na impressora virtual: %v", err)
+	}
+	log.Printf("Impressora virtual [%s]: job %s gravado em %s (%d bytes)", job.Printer, job.ID, dir, len(data))
+	return nil
+}
+
+// virtualPrinter retorna a impressora virtual configurada com o nome
+func virtualPrinter(name string) (VirtualPrinter, bool) {
+	if GlobalConfig == nil {
+		return VirtualPrinter{}, false
+	}
+	v, ok := GlobalConfig.VirtualPrinters[name]
+	return v, ok
+}
+
+// status retorna o estado da impressora virtual, com a falha simulada
+func (v VirtualPrinter) status() PrinterStatus {
+	state := escpos.StateReady
+	switch v.Simulate {
+	case SimulatePaperOut:
+		state = escpos.StatePaperOut
+	case SimulateOffline:
+		state = escpos.StateOffline
+	}
+	return PrinterStatus{State: state, CheckedAt: time.Now()}
+}
+
+// virtualPrinterNames lista as impressoras virtuais configuradas
+func virtualPrinterNames() []string {
+	if GlobalConfig == nil {
+		return nil
+	}
+	names := make([]string, 0, len(GlobalConfig.VirtualPrinters))
+	for name := range GlobalConfig.VirtualPrinters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// defaultVirtualPrinter retorna a impressora virtual marcada como padrão
+func defaultVirtualPrinter() (string, bool) {
+	for _, name := range virtualPrinterNames() {
+		if GlobalConfig.VirtualPrinters[name].Default {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// sendToPrinter entrega os bytes do job à impressora virtual, à direta ou ao
+// spooler do sistema
+func sendToPrinter(job *Job, data []byte) error {
+	if v, ok := virtualPrinter(job.Printer); ok {
+		return v.print(job, data)
+	}
+	if p, ok := directPrinter(job.Printer); ok {
+		return printDirect(job, p, data)
+	}
+	id, err := printToOS(job.Printer, data)
+	job.SystemJobID = id
+	return err
+}
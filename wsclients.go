@@ -0,0 +1,65 @@
+package main
+
+import (
+	"log"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// Event é uma notificação enviada pelo agente sem requisição do navegador
+type Event struct {
+	Event string      `json:"event"`
+	Data  interface{} `json:"data,omitempty"`
+}
+
+// wsConn serializa as escritas na conexão, permitindo enviar eventos de outras goroutines
+type wsConn struct {
+	*websocket.Conn
+	mu sync.Mutex
+}
+
+func (c *wsConn) WriteJSON(v interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Conn.WriteJSON(v)
+}
+
+var (
+	wsClientsMu sync.Mutex
+	wsClients   = map[*wsConn]bool{}
+)
+
+func registerWSClient(conn *websocket.Conn) *wsConn {
+	c := &wsConn{Conn: conn}
+	wsClientsMu.Lock()
+	wsClients[c] = true
+	wsClientsMu.Unlock()
+	return c
+}
+
+func unregisterWSClient(c *wsConn) {
+	wsClientsMu.Lock()
+	delete(wsClients, c)
+	wsClientsMu.Unlock()
+}
+
+// broadcastEvent envia o evento para todos os navegadores conectados
+func broadcastEvent(event Event) int {
+	wsClientsMu.Lock()
+	clients := make([]*wsConn, 0, len(wsClients))
+	for c := range wsClients {
+		clients = append(clients, c)
+	}
+	wsClientsMu.Unlock()
+
+	sent := 0
+	for _, c := range clients {
+		if err := c.WriteJSON(event); err != nil {
+			log.Printf("WebSocket: Erro ao enviar evento [%s] para %s: %v", event.Event, c.RemoteAddr(), err)
+			continue
+		}
+		sent++
+	}
+	return sent
+}